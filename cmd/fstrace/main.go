@@ -0,0 +1,129 @@
+// Command fstrace records the filesystem events air's watcher would see in an
+// example directory and replays them as file operations.
+//
+//	fstrace record [-c .air.toml] [-o trace.jsonl] [-duration 30s] example-dir
+//	fstrace replay [-speed 1] [-dir example-dir] trace.jsonl
+//
+// record watches the directories the example's .air.toml lets air watch and
+// writes every raw fsnotify event as a JSON line until interrupted. replay
+// performs a trace again, with its original timing, so a double build or a
+// missed event can be reproduced from an attached trace.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/airconfig"
+	"github.com/air-verse/air-reproducible-example/internal/fstrace"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: fstrace <command> [flags] [args]
+
+commands:
+  record  write the fsnotify events of an example directory as JSON lines
+  replay  perform a recorded trace as file operations`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "record":
+		err = recordCmd(args)
+	case "replay":
+		err = replayCmd(args)
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "fstrace: unknown command %q\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fstrace:", err)
+		os.Exit(1)
+	}
+}
+
+func recordCmd(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	cfg := fs.String("c", "", "air config; <dir>/.air.toml by default")
+	out := fs.String("o", "-", "trace file, - for stdout")
+	duration := fs.Duration("duration", 0, "stop after this long instead of on Ctrl-C")
+	maxData := fs.Int64("max-data", 1<<20, "largest file content kept per WRITE event")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("record takes one example directory")
+	}
+	if *cfg == "" {
+		*cfg = filepath.Join(fs.Arg(0), airconfig.FileName)
+	}
+
+	r, err := fstrace.NewRecorder(*cfg)
+	if err != nil {
+		return err
+	}
+	r.MaxData = *maxData
+	r.Log = os.Stderr
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+	return r.Record(ctx, w)
+}
+
+func replayCmd(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	speed := fs.Float64("speed", 1, "timing multiplier; 0 replays without pauses")
+	dir := fs.String("dir", ".", "directory the trace's paths are relative to")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("replay takes one trace file")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	events, err := fstrace.Read(f)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	start := time.Now()
+	if err := fstrace.Replay(ctx, events, *dir, *speed); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "replayed %d events in %s\n", len(events), time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/air-verse/air-reproducible-example/internal/airbuild"
+	"github.com/air-verse/air-reproducible-example/internal/bisect"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+)
+
+func bisectCmd(args []string) error {
+	fs := flag.NewFlagSet("bisect", flag.ExitOnError)
+	good := fs.String("good", "", "air ref where the example behaves one way")
+	bad := fs.String("bad", "", "air ref where it behaves the other way")
+	root := fs.String("root", ".", "repository root holding the examples")
+	repo := fs.String("air-repo", airbuild.DefaultRepo, "air git checkout to build from")
+	cache := fs.String("cache", "", "directory for built air binaries (default: user cache dir)")
+	variant := fs.String("variant", "", `editor and config of the variant to bisect, e.g. "remote" or ".air.follow.toml"`)
+	fs.Parse(args)
+
+	if *good == "" || *bad == "" || fs.NArg() != 1 {
+		return errors.New("usage: repro bisect -good <ref> -bad <ref> [-variant name] <example>")
+	}
+	all, err := scenario.Discover(*root)
+	if err != nil {
+		return err
+	}
+	selected, err := scenario.Select(all, fs.Args())
+	if err != nil {
+		return err
+	}
+	sc, err := pickVariant(selected[0], *variant)
+	if err != nil {
+		return err
+	}
+	builder, err := airbuild.New(*repo)
+	if err != nil {
+		return err
+	}
+	if *cache != "" {
+		builder.Cache = *cache
+	}
+
+	verdictAt := func(ref string) (runner.Verdict, error) {
+		bin, err := builder.Build(ref)
+		if err != nil {
+			return "", err
+		}
+		res := scenario.Execute(sc, bin.Path)
+		if res.Verdict == runner.Error || res.Verdict == runner.Skip {
+			return res.Verdict, fmt.Errorf("%s: %s", res.Verdict, res.Reason)
+		}
+		return res.Verdict, nil
+	}
+
+	goodCommit, err := builder.Resolve(*good)
+	if err != nil {
+		return err
+	}
+	badCommit, err := builder.Resolve(*bad)
+	if err != nil {
+		return err
+	}
+	// Fixes are searched from the buggy ref forward, regressions from the
+	// good ref forward: whichever ref is older starts the range.
+	older, newer := goodCommit, badCommit
+	if !builder.IsAncestor(older, newer) {
+		older, newer = newer, older
+	}
+	commits, err := builder.Range(older, newer)
+	if err != nil {
+		return err
+	}
+	oldVerdict, err := verdictAt(older)
+	if err != nil {
+		return fmt.Errorf("%s at %s: %w", sc.Name(), builder.Describe(older), err)
+	}
+	newVerdict, err := verdictAt(newer)
+	if err != nil {
+		return fmt.Errorf("%s at %s: %w", sc.Name(), builder.Describe(newer), err)
+	}
+	fmt.Fprintf(os.Stderr, "bisecting %s over %d commits: %s at %s, %s at %s\n",
+		sc.Name(), len(commits), oldVerdict, builder.Describe(older), newVerdict, builder.Describe(newer))
+
+	res, err := bisect.Search(commits, oldVerdict, newVerdict, verdictAt, func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "  "+format+"\n", args...)
+	})
+	if err != nil {
+		return err
+	}
+	if res.First == "" {
+		fmt.Printf("could not isolate the change (%s -> %s); it is one of:\n", oldVerdict, newVerdict)
+		for _, c := range res.Candidates {
+			fmt.Println("  " + builder.Describe(c))
+		}
+		return nil
+	}
+	fmt.Printf("%s changed from %s to %s at:\n  %s\n", sc.Name(), oldVerdict, newVerdict, builder.Describe(res.First))
+	return nil
+}
+
+// pickVariant returns the variant of sc named name. An example with
+// several variants needs one named: they can change at different commits.
+func pickVariant(sc *scenario.Scenario, name string) (*scenario.Scenario, error) {
+	variants := sc.Variants()
+	if name == "" && len(variants) == 1 {
+		return variants[0], nil
+	}
+	var names []string
+	for _, v := range variants {
+		if v.Variant() == name {
+			return v, nil
+		}
+		names = append(names, strconv.Quote(v.Variant()))
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%s has %d variants; pick one with -variant: %s", sc.Name(), len(variants), strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("%s has no variant %q; it has %s", sc.Name(), name, strings.Join(names, ", "))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/air-verse/air-reproducible-example/internal/catalog"
+)
+
+func catalogCmd(args []string) error {
+	fs := flag.NewFlagSet("catalog", flag.ExitOnError)
+	root := fs.String("root", ".", "repository root holding the examples")
+	check := fs.Bool("check", false, "do not write; fail if an example lacks meta.toml or README.md is out of date")
+	fs.Parse(args)
+	return updateCatalog(*root, *check)
+}
+
+// updateCatalog regenerates the sample list in root/README.md, or only
+// reports that it is stale when check is set.
+func updateCatalog(root string, check bool) error {
+	examples, err := catalog.Discover(root)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root, "README.md")
+	old, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	readme, err := catalog.Update(string(old), catalog.Render(examples))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if readme == string(old) {
+		return nil
+	}
+	if check {
+		return errors.New(path + ": catalog is out of date; run go run ./cmd/repro catalog")
+	}
+	if err := os.WriteFile(path, []byte(readme), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: catalog updated with %d examples\n", path, len(examples))
+	return nil
+}
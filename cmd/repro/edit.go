@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/air-verse/air-reproducible-example/internal/editsave"
+)
+
+func editCmd(args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	editor := fs.String("editor", string(editsave.Remote), "how to save the file")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: repro edit [-editor remote] <file> <pattern> <replacement>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	how, err := editsave.Parse(*editor)
+	if err != nil {
+		return err
+	}
+	file := fs.Arg(0)
+	re, err := regexp.Compile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if !re.Match(data) {
+		return fmt.Errorf("%s: no match for %q", file, fs.Arg(1))
+	}
+	return editsave.Save(file, re.ReplaceAll(data, []byte(fs.Arg(2))), how)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/air-verse/air-reproducible-example/internal/airconfig"
+	"github.com/air-verse/air-reproducible-example/internal/catalog"
+)
+
+func lintCmd(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	root := fs.String("root", ".", "repository root holding the examples")
+	target := fs.String("air-version", airconfig.Latest, "air release the configs must work with")
+	fs.Parse(args)
+
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		var err error
+		if dirs, err = catalog.Dirs(*root); err != nil {
+			return err
+		}
+	} else {
+		for i, d := range dirs {
+			dirs[i] = filepath.Join(*root, filepath.Base(filepath.Clean(d)))
+		}
+	}
+
+	problems := 0
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, airconfig.FileName)); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		findings, err := airconfig.LintExample(dir, *target)
+		if err != nil {
+			return err
+		}
+		for _, f := range findings {
+			fmt.Println(f)
+			if !f.Expected {
+				problems++
+			}
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("%d unexpected finding(s) for air %s", problems, *target)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Command repro runs the reproduction scenarios of this repository against
+// an air binary.
+//
+//	repro run [-air path] [-root dir] [-parallel n] [example ...]
+//	repro matrix -versions v1.52.0,v1.53.0,pull/856 [example ...]
+//	repro bisect -good v1.52.0 -bad v1.53.0 include-file-issue-545
+//	repro watchers [-poll-interval 500ms] [example ...]
+//	repro edit [-editor remote] file pattern replacement
+//	repro stress [-files 20000] [-depth 6] [-keep] [example]
+//	repro sse [-direct url -proxy url] [-events 4] [-max-delay 500ms] [example]
+//	repro tabs [-tabs 7] [-shared] [-http1] [-config .air.toml] [-target proxy] url|example
+//	repro catalog [-check]
+//	repro ports
+//	repro lint [-air-version v1.67.4] [example ...]
+//	repro new [-template http|gin|proxy] 123 short-slug
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: repro <command> [flags] [args]
+
+commands:
+  run     run example scenarios and print their verdicts
+  matrix  build air at several refs and run the scenarios against each
+  bisect  find the air commit where an example's verdict changed
+  watchers run examples with fsnotify and with polling and compare them
+  edit    replace text in a file the way an editor saves, or with no inotify event
+  stress  fill an example with a large generated tree and measure air on it
+  sse     read an event stream directly and through air's proxy and compare the events
+  tabs    open a page in several tabs of an emulated browser and time each page load
+  catalog regenerate the README sample list from each example's meta.toml
+  ports   list the default ports of the examples and which ones collide
+  lint    check every .air.toml against the keys an air release accepts
+  new     create a reproduction directory for an air issue from a template`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "run":
+		err = runCmd(args)
+	case "matrix":
+		err = matrixCmd(args)
+	case "bisect":
+		err = bisectCmd(args)
+	case "watchers":
+		err = watchersCmd(args)
+	case "edit":
+		err = editCmd(args)
+	case "stress":
+		err = stressCmd(args)
+	case "sse":
+		err = sseCmd(args)
+	case "tabs":
+		err = tabsCmd(args)
+	case "catalog":
+		err = catalogCmd(args)
+	case "ports":
+		err = portsCmd(args)
+	case "lint":
+		err = lintCmd(args)
+	case "new":
+		err = newCmd(args)
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "repro: unknown command %q\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "repro:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/air-verse/air-reproducible-example/internal/airbuild"
+	"github.com/air-verse/air-reproducible-example/internal/report"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+)
+
+func matrixCmd(args []string) error {
+	fs := flag.NewFlagSet("matrix", flag.ExitOnError)
+	versions := fs.String("versions", "", "comma-separated air refs (tags, branches, commits, pull/N) or paths to air binaries")
+	root := fs.String("root", ".", "repository root holding the examples")
+	repo := fs.String("air-repo", airbuild.DefaultRepo, "air git checkout to build from")
+	cache := fs.String("cache", "", "directory for built air binaries (default: user cache dir)")
+	reportDir := fs.String("report", "reports", "directory for repro.json and junit.xml (empty to disable)")
+	fs.Parse(args)
+
+	refs := splitList(*versions)
+	if len(refs) == 0 {
+		return errors.New("matrix: -versions is required")
+	}
+	all, err := scenario.Discover(*root)
+	if err != nil {
+		return err
+	}
+	selected, err := scenario.Select(all, fs.Args())
+	if err != nil {
+		return err
+	}
+	// Every editor and config variant gets its own row.
+	var scenarios []*scenario.Scenario
+	for _, sc := range selected {
+		scenarios = append(scenarios, sc.Variants()...)
+	}
+	builder, err := airbuild.New(*repo)
+	if err != nil {
+		return err
+	}
+	if *cache != "" {
+		builder.Cache = *cache
+	}
+
+	rep := report.New()
+	columns := make([][]runner.Result, len(refs))
+	for i, ref := range refs {
+		bin, err := resolveVersion(builder, ref)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "== air %s (%s)\n", ref, bin.Short())
+		for _, sc := range scenarios {
+			res := scenario.Execute(sc, bin.Path)
+			fmt.Fprintf(os.Stderr, "   %-5s %s %s\n", res.Verdict, res.Example, res.Reason)
+			columns[i] = append(columns[i], res)
+			rep.Add(res)
+		}
+	}
+	printMatrix(refs, scenarios, columns)
+	rep.Finish()
+	return writeReport(rep, *reportDir)
+}
+
+// resolveVersion uses ref as a binary when it names an existing file and
+// builds it from the air checkout otherwise.
+func resolveVersion(b *airbuild.Builder, ref string) (airbuild.Binary, error) {
+	if info, err := os.Stat(ref); err == nil && !info.IsDir() {
+		return airbuild.Binary{Ref: ref, Commit: "local", Path: ref}, nil
+	}
+	return b.Build(ref)
+}
+
+// printMatrix writes a markdown table with one row per example and one
+// column per air version.
+func printMatrix(refs []string, scenarios []*scenario.Scenario, columns [][]runner.Result) {
+	fmt.Printf("| example | %s |\n", strings.Join(refs, " | "))
+	fmt.Printf("|---%s|\n", strings.Repeat("|---", len(refs)))
+	for row, sc := range scenarios {
+		cells := make([]string, len(refs))
+		for col := range refs {
+			cells[col] = string(columns[col][row].Verdict)
+		}
+		fmt.Printf("| %s | %s |\n", sc.Name(), strings.Join(cells, " | "))
+	}
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
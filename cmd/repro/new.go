@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/air-verse/air-reproducible-example/internal/scaffold"
+)
+
+func newCmd(args []string) error {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	tmpl := fs.String("template", "http", "app template: "+strings.Join(scaffold.Templates, ", "))
+	root := fs.String("root", ".", "repository root holding the examples")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: repro new [-template http|gin|proxy] [-root dir] <issue> [slug]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	issue, err := strconv.Atoi(strings.TrimPrefix(fs.Arg(0), "#"))
+	if err != nil {
+		return fmt.Errorf("issue number: %w", err)
+	}
+
+	dir, err := scaffold.Create(scaffold.Options{
+		Root:     *root,
+		Issue:    issue,
+		Slug:     fs.Arg(1),
+		Template: *tmpl,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "created %s from the %s template\n", dir, *tmpl)
+	if *tmpl == "gin" {
+		tidy := exec.Command("go", "mod", "tidy")
+		tidy.Dir = dir
+		tidy.Stdout, tidy.Stderr = os.Stderr, os.Stderr
+		if err := tidy.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "go mod tidy failed (%v); run it in %s before air\n", err, dir)
+		}
+	}
+	if err := updateCatalog(*root, false); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "next: fill in the TODOs in README.md, meta.toml and scenario.toml, then check it with\n  go run ./cmd/repro run %s\n", dir)
+	return nil
+}
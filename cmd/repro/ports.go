@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/air-verse/air-reproducible-example/internal/catalog"
+	"github.com/air-verse/air-reproducible-example/internal/ports"
+)
+
+func portsCmd(args []string) error {
+	fs := flag.NewFlagSet("ports", flag.ExitOnError)
+	root := fs.String("root", ".", "repository root holding the examples")
+	fs.Parse(args)
+
+	examples, err := catalog.Discover(*root)
+	if err != nil {
+		return err
+	}
+	type use struct{ example, role string }
+	byPort := map[int][]use{}
+	for _, e := range examples {
+		for role, p := range e.Ports {
+			byPort[p] = append(byPort[p], use{e.Name(), role})
+		}
+	}
+	list := make([]int, 0, len(byPort))
+	for p := range byPort {
+		list = append(list, p)
+	}
+	sort.Ints(list)
+
+	const row = "%-6s  %-5s  %s\n"
+	fmt.Printf(row, "PORT", "BUSY", "EXAMPLES")
+	for _, p := range list {
+		var names []string
+		for _, u := range byPort[p] {
+			names = append(names, fmt.Sprintf("%s (%s, $%s)", u.example, u.role, ports.Env(u.role)))
+		}
+		sort.Strings(names)
+		busy := ""
+		if ports.Busy(p) {
+			busy = "yes"
+		}
+		fmt.Printf(row, fmt.Sprint(p), busy, strings.Join(names, ", "))
+	}
+	if c := ports.Collisions(examples); len(c) > 0 {
+		fmt.Printf("\n%d default port(s) are shared; run those examples one at a time with air, or through repro run, which leases free ports.\n", len(c))
+	}
+	return nil
+}
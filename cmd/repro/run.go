@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/report"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+)
+
+func runCmd(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	air := fs.String("air", "", "air binary (default $AIR_BIN or air in PATH)")
+	root := fs.String("root", ".", "repository root holding the examples")
+	verbose := fs.Bool("v", false, "print the air log of every example that did not pass")
+	reportDir := fs.String("report", "reports", "directory for repro.json and junit.xml (empty to disable)")
+	parallel := fs.Int("parallel", 1, "number of examples to run at once; each gets its own ports")
+	fs.Parse(args)
+
+	all, err := scenario.Discover(*root)
+	if err != nil {
+		return err
+	}
+	scenarios, err := scenario.Select(all, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	rep := report.New()
+	const row = "%-7s  %-36s  %7s  %s\n"
+	fmt.Printf(row, "VERDICT", "EXAMPLE", "TIME", "REASON")
+	// Rows are printed as examples finish; the report keeps scenario order.
+	// The editor variants of one example edit the same files, so they run
+	// one after the other.
+	results := make([][]runner.Result, len(scenarios))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(*parallel, 1))
+	for i, sc := range scenarios {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			for _, v := range sc.Variants() {
+				res := scenario.Execute(v, *air)
+				results[i] = append(results[i], res)
+				mu.Lock()
+				fmt.Printf(row, res.Verdict, res.Example, res.Duration.Round(100*time.Millisecond), res.Reason)
+				if *verbose && (res.Verdict == runner.Bug || res.Verdict == runner.Error) {
+					fmt.Printf("--- air log of %s\n%s\n", res.Example, res.AirLog)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	failed := 0
+	for _, variants := range results {
+		for _, res := range variants {
+			rep.Add(res)
+			if res.Verdict == runner.Error {
+				failed++
+			}
+		}
+	}
+	rep.Finish()
+	if err := writeReport(rep, *reportDir); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d example(s) ended in ERROR", failed)
+	}
+	return nil
+}
+
+func writeReport(rep *report.Report, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if err := rep.WriteDir(dir); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "report written to %s and %s\n", filepath.Join(dir, report.JSONFile), filepath.Join(dir, report.JUnitFile))
+	return nil
+}
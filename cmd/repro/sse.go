@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+	"github.com/air-verse/air-reproducible-example/internal/ssestream"
+)
+
+func sseCmd(args []string) error {
+	fs := flag.NewFlagSet("sse", flag.ExitOnError)
+	direct := fs.String("direct", "http://localhost:3002/sse", "event stream served by the app")
+	proxy := fs.String("proxy", "http://localhost:3082/sse", "the same stream through Air's proxy")
+	air := fs.String("air", "", "air binary used with an example (default $AIR_BIN or air in PATH)")
+	root := fs.String("root", ".", "repository root holding the examples")
+	path := fs.String("path", "/sse", "stream path used with an example")
+	events := fs.Int("events", 4, "events to read from each stream")
+	maxDelay := fs.Duration("max-delay", 500*time.Millisecond, "how much later an event may come through the proxy")
+	timeout := fs.Duration("timeout", 30*time.Second, "how long to wait for the events")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: repro sse [flags] [example]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	compare := func(direct, proxy string) error {
+		return compareStreams(direct, proxy, *events, *maxDelay, *timeout)
+	}
+	if fs.NArg() == 0 {
+		return compare(*direct, *proxy)
+	}
+
+	// Start the example under air on leased ports and read its streams.
+	dir := filepath.Join(*root, filepath.Base(filepath.Clean(fs.Arg(0))))
+	sc, err := scenario.LoadExample(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := sc.Ports["proxy"]; !ok {
+		return fmt.Errorf("%s: meta.toml declares no proxy port", sc.Name())
+	}
+	var cmpErr error
+	res := scenario.ExecuteFunc(sc, *air, func(run *scenario.Scenario, s *runner.Session) error {
+		if run.Ready != "" {
+			if err := s.WaitLog(run.Ready, 1, 2*time.Minute); err != nil {
+				return err
+			}
+		}
+		url := func(role string) string {
+			return "http://localhost:" + strconv.Itoa(run.Ports[role]) + *path
+		}
+		cmpErr = compare(url("app"), url("proxy"))
+		return nil
+	})
+	if res.Verdict == runner.Error {
+		return fmt.Errorf("%s: %s", res.Example, res.Reason)
+	}
+	return cmpErr
+}
+
+// compareStreams reads both streams at the same time, prints every event
+// with its arrival and the read and chunk that carried it, and fails when
+// the proxy coalesced or delayed events.
+func compareStreams(directURL, proxyURL string, events int, maxDelay, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	type result struct {
+		s   *ssestream.Stream
+		err error
+	}
+	directCh, proxyCh := make(chan result, 1), make(chan result, 1)
+	go func() {
+		s, err := ssestream.Record(ctx, directURL, events)
+		directCh <- result{s, err}
+	}()
+	go func() {
+		s, err := ssestream.Record(ctx, proxyURL, events)
+		proxyCh <- result{s, err}
+	}()
+	d, p := <-directCh, <-proxyCh
+	if d.err != nil {
+		return fmt.Errorf("direct: %w", d.err)
+	}
+	if p.err != nil {
+		return fmt.Errorf("proxy: %w", p.err)
+	}
+	if len(d.s.Events) == 0 {
+		return fmt.Errorf("direct: no event from %s within %s", directURL, timeout)
+	}
+
+	c := ssestream.Compare(d.s, p.s, maxDelay)
+	fmt.Printf("direct  %s  %s\n", directURL, describe(d.s))
+	fmt.Printf("proxy   %s  %s\n\n", proxyURL, describe(p.s))
+	fmt.Println("| Event | Direct | Proxy | Delay | Direct read/chunk | Proxy read/chunk | |")
+	fmt.Println("|---|---|---|---|---|---|---|")
+	for _, r := range c.Rows {
+		proxyAt, delay, proxyPos := "-", "-", "-"
+		if r.Proxy != nil {
+			proxyAt = r.Proxy.At.Round(time.Millisecond).String()
+			delay = r.Delay.Round(time.Millisecond).String()
+			proxyPos = position(p.s, *r.Proxy)
+		}
+		var flags []string
+		if r.Coalesced {
+			flags = append(flags, "coalesced")
+		}
+		if r.Late {
+			flags = append(flags, "late")
+		}
+		fmt.Printf("| %d | %s | %s | %s | %s | %s | %s |\n", r.Index,
+			r.Direct.At.Round(time.Millisecond), proxyAt, delay,
+			position(d.s, *r.Direct), proxyPos, strings.Join(flags, ", "))
+	}
+	if c.OK() {
+		fmt.Printf("\nthe proxy forwarded all %d events on their own and within %s\n", len(c.Rows), maxDelay)
+		return nil
+	}
+	return fmt.Errorf("the proxy coalesced %d and delayed %d of %d events (more than %s late or missing)", c.Coalesced, c.Late, len(c.Rows), maxDelay)
+}
+
+// describe sums up a stream's framing: its reads and chunk sizes.
+func describe(s *ssestream.Stream) string {
+	if s.Status == 0 {
+		return "no response"
+	}
+	var sizes []string
+	for _, c := range s.Chunks {
+		sizes = append(sizes, fmt.Sprintf("%#x", c.Size))
+	}
+	framing := "not chunked"
+	if s.Chunked {
+		framing = "chunks " + strings.Join(sizes, " ")
+	}
+	desc := fmt.Sprintf("status %d, %d events, %d reads, %s", s.Status, len(s.Events), len(s.Reads), framing)
+	if s.Ended > 0 {
+		desc += fmt.Sprintf(", ended after %s", s.Ended.Round(time.Millisecond))
+	}
+	return desc
+}
+
+// position names the read, and chunk if any, that completed e.
+func position(s *ssestream.Stream, e ssestream.Event) string {
+	pos := fmt.Sprintf("read %d (%dB)", e.Read, s.Reads[e.Read].Size)
+	if e.Chunk >= 0 && e.Chunk < len(s.Chunks) {
+		pos += fmt.Sprintf(", chunk %d (%#x)", e.Chunk, s.Chunks[e.Chunk].Size)
+	}
+	return pos
+}
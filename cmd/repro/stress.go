@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+	"github.com/air-verse/air-reproducible-example/internal/stress"
+)
+
+func stressCmd(args []string) error {
+	fs := flag.NewFlagSet("stress", flag.ExitOnError)
+	air := fs.String("air", "", "air binary (default $AIR_BIN or air in PATH)")
+	root := fs.String("root", ".", "repository root holding the examples")
+	files := fs.Int("files", 20000, "number of files to generate")
+	depth := fs.Int("depth", 6, "directory levels of each generated tree")
+	idle := fs.Duration("idle", 5*time.Second, "how long to sample air's CPU while nothing changes")
+	quiet := fs.Duration("quiet", 3*time.Second, "how long an edit in an excluded tree may take to rebuild anyway")
+	keep := fs.Bool("keep", false, "leave the generated trees in place")
+	clean := fs.Bool("clean", false, "only remove trees left by -keep")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: repro stress [flags] [example]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	name := "large-tree-stress"
+	if fs.NArg() == 1 {
+		name = fs.Arg(0)
+	}
+	dir := filepath.Join(*root, filepath.Base(filepath.Clean(name)))
+	if *clean {
+		return stress.Clean(dir)
+	}
+	sc, err := scenario.Load(dir)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	trees, err := stress.Generate(dir, stress.Spec{Files: *files, Depth: *depth})
+	if !*keep {
+		defer stress.Clean(dir)
+	}
+	if err != nil {
+		return err
+	}
+	for _, t := range trees {
+		fmt.Fprintf(os.Stderr, "generated %6d files in %5d dirs under %s\n", t.Files, t.Dirs, t.Root)
+	}
+	fmt.Fprintf(os.Stderr, "generated in %s; starting air\n", time.Since(start).Round(time.Millisecond))
+
+	res, r := stress.Measure(sc, *air, stress.Options{Idle: *idle, Quiet: *quiet})
+	if res.Verdict == runner.Error {
+		return fmt.Errorf("%s: %s", res.Example, res.Reason)
+	}
+	fmt.Printf("startup   %s\n", r.Startup.Round(time.Millisecond))
+	fmt.Printf("watches   %d\n", r.Watches)
+	fmt.Printf("rss       %.1f MiB\n", float64(r.RSS)/(1<<20))
+	fmt.Printf("idle cpu  %.1f%% over %s\n", r.IdleCPU, *idle)
+	wrong := 0
+	for _, e := range r.Edits {
+		verdict := "ok"
+		if e.Rebuilt != e.Watched {
+			verdict = "WRONG"
+			wrong++
+		}
+		fmt.Printf("edit      %-12s watched=%-5t rebuilt=%-5t %s\n", e.Tree.Name, e.Watched, e.Rebuilt, verdict)
+	}
+	if wrong > 0 {
+		return fmt.Errorf("%d edit(s) rebuilt when they should not have or the other way round", wrong)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+	"github.com/air-verse/air-reproducible-example/internal/tabs"
+)
+
+func tabsCmd(args []string) error {
+	fs := flag.NewFlagSet("tabs", flag.ExitOnError)
+	opts := tabs.Options{}
+	fs.IntVar(&opts.Tabs, "tabs", 7, "tabs to open one after the other")
+	fs.IntVar(&opts.PerHost, "per-host", tabs.DefaultPerHost, "connections the browser opens to one host")
+	fs.BoolVar(&opts.Shared, "shared", false, "share one event stream between tabs through the page's SharedWorker")
+	fs.BoolVar(&opts.HTTP1, "http1", false, "stay on HTTP/1.1 for https:// pages")
+	fs.DurationVar(&opts.Wait, "wait", 5*time.Second, "how long a tab waits for its page")
+	air := fs.String("air", "", "air binary used with an example (default $AIR_BIN or air in PATH)")
+	root := fs.String("root", ".", "repository root holding the examples")
+	config := fs.String("config", "", "air config of the example (default .air.toml)")
+	target := fs.String("target", "proxy", "port of the example to open: proxy or app")
+	https := fs.Bool("https", false, "open the example over https://")
+	path := fs.String("path", "/", "page opened with an example")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: repro tabs [flags] url|example")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	arg := fs.Arg(0)
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		return openTabs(arg, opts)
+	}
+
+	// Start the example under air on leased ports and open its page.
+	dir := filepath.Join(*root, filepath.Base(filepath.Clean(arg)))
+	sc, err := scenario.LoadExample(dir)
+	if err != nil {
+		return err
+	}
+	if *config != "" {
+		sc = sc.WithConfig(*config)
+	}
+	if _, ok := sc.Ports[*target]; !ok {
+		return fmt.Errorf("%s: meta.toml declares no %s port", sc.Name(), *target)
+	}
+	var tabsErr error
+	res := scenario.ExecuteFunc(sc, *air, func(run *scenario.Scenario, s *runner.Session) error {
+		if run.Ready != "" {
+			if err := s.WaitLog(run.Ready, 1, 2*time.Minute); err != nil {
+				return err
+			}
+		}
+		scheme := "http"
+		if *https {
+			scheme = "https"
+		}
+		tabsErr = openTabs(scheme+"://localhost:"+strconv.Itoa(run.Ports[*target])+*path, opts)
+		return nil
+	})
+	if res.Verdict == runner.Error {
+		return fmt.Errorf("%s: %s", res.Example, res.Reason)
+	}
+	return tabsErr
+}
+
+// openTabs opens the tabs, prints a line for each and fails when a page
+// did not load.
+func openTabs(pageURL string, opts tabs.Options) error {
+	b, err := tabs.Open(context.Background(), pageURL, opts)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	fmt.Printf("%s in %d tabs, %d connections per host\n\n", pageURL, opts.Tabs, opts.PerHost)
+	fmt.Println("| Tab | Status | Proto | TTFB | Load | Event stream | |")
+	fmt.Println("|---|---|---|---|---|---|---|")
+	failed := 0
+	for _, t := range b.Tabs {
+		status, proto, load := "-", "-", "-"
+		if t.Status != 0 {
+			status, proto, load = strconv.Itoa(t.Status), t.Proto, short(t.Load)
+		}
+		stream := "-"
+		switch {
+		case t.Stream != "" && t.StreamTTFB > 0:
+			stream = "open after " + short(t.StreamTTFB)
+		case t.Stream != "":
+			stream = "waiting for a connection"
+		case t.Err == nil && opts.Shared:
+			stream = "shared"
+		}
+		note := ""
+		if t.Err != nil {
+			failed++
+			note = t.Err.Error()
+		}
+		fmt.Printf("| %d | %s | %s | %s | %s | %s | %s |\n", t.Index, status, proto,
+			short(t.TTFB), load, stream, note)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tabs did not load within %s", failed, opts.Tabs, opts.Wait)
+	}
+	fmt.Printf("\nall %d tabs loaded\n", opts.Tabs)
+	return nil
+}
+
+// short rounds d to milliseconds, or to tens of microseconds below one.
+func short(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/report"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+)
+
+// watcherRun is one scenario run with a forced watcher.
+type watcherRun struct {
+	res runner.Result
+	m   scenario.Metrics
+}
+
+func watchersCmd(args []string) error {
+	fs := flag.NewFlagSet("watchers", flag.ExitOnError)
+	air := fs.String("air", "", "air binary (default $AIR_BIN or air in PATH)")
+	root := fs.String("root", ".", "repository root holding the examples")
+	interval := fs.Duration("poll-interval", 500*time.Millisecond, "poll_interval of the polling run; air's minimum is 500ms")
+	reportDir := fs.String("report", "reports", "directory for repro.json and junit.xml (empty to disable)")
+	fs.Parse(args)
+
+	all, err := scenario.Discover(*root)
+	if err != nil {
+		return err
+	}
+	scenarios, err := scenario.Select(all, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	watchers := []scenario.Watcher{{}, {Poll: true, Interval: *interval}}
+	rep := report.New()
+	var names []string
+	var rows [][]watcherRun
+	for _, sc := range scenarios {
+		for _, v := range sc.Variants() {
+			row := make([]watcherRun, len(watchers))
+			for i, w := range watchers {
+				res, m := scenario.Measure(v.WithWatcher(w), *air)
+				fmt.Fprintf(os.Stderr, "   %-5s %s %s\n", res.Verdict, res.Example, res.Reason)
+				rep.Add(res)
+				row[i] = watcherRun{res, m}
+			}
+			names = append(names, v.Name())
+			rows = append(rows, row)
+		}
+	}
+
+	fmt.Printf("| example | fsnotify | poll (%s) | differences |\n", *interval)
+	fmt.Println("|---|---|---|---|")
+	differ := 0
+	for i, row := range rows {
+		diffs := watcherDiffs(row[0], row[1])
+		if len(diffs) > 0 {
+			differ++
+		}
+		fmt.Printf("| %s | %s | %s | %s |\n", names[i], row[0], row[1], strings.Join(diffs, "; "))
+	}
+	fmt.Fprintf(os.Stderr, "%d of %d example(s) behave differently under polling\n", differ, len(rows))
+	rep.Finish()
+	return writeReport(rep, *reportDir)
+}
+
+func (r watcherRun) String() string {
+	s := fmt.Sprintf("%s, %d build(s), %d start(s)", r.res.Verdict, r.m.Builds, r.m.Starts)
+	if len(r.m.Latency) > 0 {
+		s += fmt.Sprintf(", %s", r.m.Median().Round(time.Millisecond))
+	}
+	if r.m.Missed > 0 {
+		s += fmt.Sprintf(", %d edit(s) missed", r.m.Missed)
+	}
+	if b := r.m.Build; b != nil {
+		s += fmt.Sprintf(", sources %.12s", b.SourceHash)
+	}
+	return s
+}
+
+// watcherDiffs lists what the two runs disagree on. Latency always differs
+// a little, so only a median more than twice the other one and over 500ms
+// apart counts, and only when both runs saw edits through.
+func watcherDiffs(a, b watcherRun) []string {
+	var diffs []string
+	if a.res.Verdict != b.res.Verdict {
+		diffs = append(diffs, fmt.Sprintf("verdict %s vs %s", a.res.Verdict, b.res.Verdict))
+	}
+	if a.m.Builds != b.m.Builds {
+		diffs = append(diffs, fmt.Sprintf("builds %d vs %d", a.m.Builds, b.m.Builds))
+	}
+	if a.m.Starts != b.m.Starts {
+		diffs = append(diffs, fmt.Sprintf("starts %d vs %d", a.m.Starts, b.m.Starts))
+	}
+	if a.m.Missed != b.m.Missed {
+		diffs = append(diffs, fmt.Sprintf("missed edits %d vs %d", a.m.Missed, b.m.Missed))
+	}
+	la, lb := a.m.Median(), b.m.Median()
+	if lo, hi := min(la, lb), max(la, lb); lo > 0 && hi > 2*lo && hi-lo > 500*time.Millisecond {
+		diffs = append(diffs, fmt.Sprintf("latency %s vs %s", la.Round(time.Millisecond), lb.Round(time.Millisecond)))
+	}
+	switch ba, bb := a.m.Build, b.m.Build; {
+	case (ba == nil) != (bb == nil):
+		diffs = append(diffs, "only one run reported provenance")
+	case ba != nil && ba.SourceHash != bb.SourceHash:
+		diffs = append(diffs, fmt.Sprintf("final sources %.12s vs %.12s", ba.SourceHash, bb.SourceHash))
+	}
+	return diffs
+}
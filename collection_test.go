@@ -0,0 +1,112 @@
+package repro
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/airconfig"
+	"github.com/air-verse/air-reproducible-example/internal/catalog"
+	"github.com/air-verse/air-reproducible-example/internal/report"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+)
+
+// TestScenarios parses every scenario.toml, so a broken scenario fails the
+// build even when air is not available.
+func TestScenarios(t *testing.T) {
+	scenarios, err := scenario.Discover(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) == 0 {
+		t.Fatalf("no %s found", scenario.FileName)
+	}
+}
+
+// TestCatalog fails when an example directory has no meta.toml or the
+// README sample list was not regenerated after a change.
+func TestCatalog(t *testing.T) {
+	examples, err := catalog.Discover(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readme, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := catalog.Update(string(readme), catalog.Render(examples))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != string(readme) {
+		t.Error("README.md catalog is out of date; run go run ./cmd/repro catalog")
+	}
+}
+
+// TestAirConfigs lints every .air.toml against the latest air release in
+// the schema. Findings an example's meta.toml expects are only logged.
+func TestAirConfigs(t *testing.T) {
+	dirs, err := catalog.Dirs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, airconfig.FileName)); err != nil {
+			continue
+		}
+		findings, err := airconfig.LintExample(dir, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range findings {
+			if f.Expected {
+				t.Log(f)
+			} else {
+				t.Error(f)
+			}
+		}
+	}
+}
+
+// TestCollection runs every scenario against the air binary named by
+// $AIR_BIN ("air" picks the one in PATH). A BUG verdict is logged rather than
+// failed: the collection exists to show which upstream bugs still reproduce.
+// The JSON and JUnit reports go to $REPRO_REPORT_DIR, or reports/.
+func TestCollection(t *testing.T) {
+	bin := os.Getenv("AIR_BIN")
+	if bin == "" {
+		t.Skip("set AIR_BIN to an air binary (or \"air\") to run the reproduction collection")
+	}
+	scenarios, err := scenario.Discover(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportDir := os.Getenv("REPRO_REPORT_DIR")
+	if reportDir == "" {
+		reportDir = "reports"
+	}
+	rep := report.New()
+	t.Cleanup(func() {
+		rep.Finish()
+		if err := rep.WriteDir(reportDir); err != nil {
+			t.Error(err)
+		}
+	})
+	for _, sc := range scenarios {
+		for _, v := range sc.Variants() {
+			t.Run(v.Name(), func(t *testing.T) {
+				res := scenario.Execute(v, bin)
+				rep.Add(res)
+				t.Logf("%s %s (%s) %s", res.Verdict, res.Example, res.Duration.Round(time.Millisecond), res.Reason)
+				switch res.Verdict {
+				case runner.Skip:
+					t.Skip(res.Reason)
+				case runner.Error:
+					t.Fatalf("air log:\n%s", res.AirLog)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package repro
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestDangerousRoot runs air in / and /root, in symlinks to them, and in a
+// project directory, all inside a throwaway root directory: air is chrooted
+// there from a new user namespace, so it can neither see nor watch the real
+// filesystem even if it does not refuse.
+func TestDangerousRoot(t *testing.T) {
+	air := dangerousAir(t)
+	root := t.TempDir()
+	if err := copyExecutable(air, filepath.Join(root, "bin", "air")); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"root", "home/user"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeProject(t, filepath.Join(root, "project"))
+	for link, target := range map[string]string{"link-to-slash": "/", "link-to-root": "/root"} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jail := &syscall.SysProcAttr{
+		Chroot:      root,
+		Cloneflags:  syscall.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+	probe := exec.Command("/bin/air", "-v")
+	probe.SysProcAttr = jail
+	if out, err := probe.CombinedOutput(); err != nil {
+		t.Skipf("cannot run air chrooted in a user namespace (disabled, or air needs more than ldd lists): %v %s", err, out)
+	}
+
+	run := func(t *testing.T, dir string) (string, bool) {
+		cmd := exec.Command("/bin/air")
+		cmd.Dir = dir
+		cmd.Env = []string{"HOME=/home/user", "PATH=/bin", "PWD=" + dir}
+		cmd.SysProcAttr = jail
+		return startAir(t, cmd)
+	}
+	for _, c := range []struct {
+		name, dir, want string
+	}{
+		{"slash", "/", refuseRoot},
+		{"slash root", "/root", refuseSlashRoot},
+		{"symlink to slash", "/link-to-slash", refuseRoot},
+		{"symlink to slash root", "/link-to-root", refuseSlashRoot},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, running := run(t, c.dir)
+			checkRefuses(t, out, running, c.want)
+		})
+	}
+	t.Run("project", func(t *testing.T) {
+		out, running := run(t, "/project")
+		checkStarts(t, out, running)
+	})
+}
+
+// copyExecutable copies bin to dst together with the shared libraries ldd
+// lists for it, at the same paths below dst's root, so it runs chrooted.
+func copyExecutable(bin, dst string) error {
+	root := filepath.Dir(filepath.Dir(dst))
+	files := []string{bin}
+	if out, err := exec.Command("ldd", bin).Output(); err == nil {
+		for _, f := range strings.Fields(string(out)) {
+			if filepath.IsAbs(f) {
+				files = append(files, f)
+			}
+		}
+	}
+	for i, f := range files {
+		to := filepath.Join(root, f)
+		if i == 0 {
+			to = dst
+		}
+		if err := copyFile(f, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,126 @@
+//go:build !windows
+
+package repro
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+)
+
+// Messages air prints when it refuses a dangerous root (issue #761).
+const (
+	refuseHome      = "refusing to run in home directory (~) - this would watch too many files. Please run air in a project directory"
+	refuseRoot      = "refusing to run in root directory (/) - this would watch too many files. Please run air in a project directory"
+	refuseSlashRoot = "refusing to run in /root directory - this would watch too many files. Please run air in a project directory"
+)
+
+// dangerousAir returns the air binary named by $AIR_BIN, skipping the test
+// when it is not set, like TestCollection.
+func dangerousAir(t *testing.T) string {
+	t.Helper()
+	bin := os.Getenv("AIR_BIN")
+	if bin == "" {
+		t.Skip("set AIR_BIN to an air binary (or \"air\") to run the dangerous root checks")
+	}
+	bin, err := runner.LocateAir(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bin
+}
+
+// startAir runs cmd for up to two seconds and returns its output and
+// whether it was still running then, i.e. whether air started watching.
+func startAir(t *testing.T, cmd *exec.Cmd) (string, bool) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	// A build air started may hold the output open after air is killed.
+	cmd.WaitDelay = 100 * time.Millisecond
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() { cmd.Wait(); close(done) }()
+	select {
+	case <-done:
+		return out.String(), false
+	case <-time.After(2 * time.Second):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), true
+	}
+}
+
+// writeProject puts a minimal Go module in dir.
+func writeProject(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":  "module project\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkRefuses fails unless air exited with want.
+func checkRefuses(t *testing.T, out string, running bool, want string) {
+	t.Helper()
+	if running || !strings.Contains(out, want) {
+		t.Errorf("air did not refuse the directory (running=%t); want %q in:\n%s", running, want, out)
+	}
+}
+
+// checkStarts fails unless air is still running and watching.
+func checkStarts(t *testing.T, out string, running bool) {
+	t.Helper()
+	if !running || strings.Contains(out, "refusing to run") {
+		t.Errorf("air did not start in a project directory (running=%t):\n%s", running, out)
+	}
+}
+
+// TestDangerousHome runs air in a throwaway home directory, set through
+// $HOME, in a symlink to it, and in a project below it. Only the project
+// may start; a real home directory is never touched.
+func TestDangerousHome(t *testing.T) {
+	air := dangerousAir(t)
+	tmp := t.TempDir()
+	home := filepath.Join(tmp, "home", "user")
+	writeProject(t, filepath.Join(home, "project"))
+	link := filepath.Join(tmp, "home-link")
+	if err := os.Symlink(home, link); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(dir string) (string, bool) {
+		cmd := exec.Command(air)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "HOME="+home, "PWD="+dir)
+		return startAir(t, cmd)
+	}
+	t.Run("home", func(t *testing.T) {
+		out, running := run(home)
+		checkRefuses(t, out, running, refuseHome)
+	})
+	t.Run("symlink to home", func(t *testing.T) {
+		out, running := run(link)
+		checkRefuses(t, out, running, refuseHome)
+	})
+	t.Run("project", func(t *testing.T) {
+		out, running := run(filepath.Join(home, "project"))
+		checkStarts(t, out, running)
+	})
+}
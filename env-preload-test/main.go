@@ -1,12 +1,20 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
 	// List of environment variables to test
 	envVars := []string{
@@ -43,7 +51,11 @@ func main() {
 	}
 	fmt.Println("======================")
 
-	port := os.Getenv("APP_PORT")
+	// PORT wins over the APP_PORT from .env so the runner can move the app.
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = os.Getenv("APP_PORT")
+	}
 	if port == "" {
 		port = "8080"
 	}
@@ -67,7 +79,7 @@ func main() {
 	fmt.Println("Press Ctrl+C to stop")
 	fmt.Println("\nTry modifying .env file to test hot reload!")
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
 		fmt.Printf("Server error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,7 @@
+module generated-code-loop
+
+go 1.21
+
+require github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+
+replace github.com/air-verse/air-reproducible-example/reprokit => ../reprokit
@@ -0,0 +1,66 @@
+// Command gen compiles a key = value file into a Go map, the way sqlc or
+// templ turn their inputs into .go files. Like many generators it stamps
+// the output with the time it ran, so the file changes on every run even
+// when the input did not.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatal("usage: gen input output")
+	}
+	in, out := os.Args[1], os.Args[2]
+	f, err := os.Open(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	messages := map[string]string{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			log.Fatalf("%s: %q is not key = value", in, line)
+		}
+		messages[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := sc.Err(); err != nil {
+		log.Fatal(err)
+	}
+	keys := make([]string, 0, len(messages))
+	for k := range messages {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gen from %s at %s. DO NOT EDIT.\n\n", in, time.Now().Format(time.RFC3339Nano))
+	fmt.Fprintf(&buf, "package main\n\nvar messages = map[string]string{\n")
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%q: %q,\n", k, messages[k])
+	}
+	fmt.Fprintf(&buf, "}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+//go:generate go run ./internal/gen messages.txt messages_gen.go
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
+)
+
+//go:embed *.go messages.txt internal/gen/*.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, messages["greeting"])
+	})
+	fmt.Println("Server starting on :" + port)
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
+		fmt.Printf("Server error: %v\n", err)
+	}
+}
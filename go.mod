@@ -0,0 +1,17 @@
+module github.com/air-verse/air-reproducible-example
+
+go 1.23
+
+require github.com/BurntSushi/toml v1.6.0
+
+require (
+	github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+	github.com/andybalholm/brotli v1.2.0
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/gorilla/websocket v1.5.3
+	github.com/klauspost/compress v1.18.0
+)
+
+require golang.org/x/sys v0.13.0 // indirect
+
+replace github.com/air-verse/air-reproducible-example/reprokit => ./reprokit
@@ -1,3 +1,7 @@
 module include-file-issue-545
 
 go 1.25.4
+
+require github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+
+replace github.com/air-verse/air-reproducible-example/reprokit => ../reprokit
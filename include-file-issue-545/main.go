@@ -1,13 +1,21 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go myfile.txt Makefile
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
 	// Read content from myfile.txt
 	content, err := os.ReadFile("myfile.txt")
@@ -24,6 +32,10 @@ func main() {
 	}
 
 	startTime := time.Now()
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
 
 	log.Printf("===========================================")
 	log.Printf("App started at: %s", startTime.Format("15:04:05.000"))
@@ -42,7 +54,9 @@ func main() {
 		fmt.Fprintf(w, "OK - Started at %s\n", startTime.Format("15:04:05.000"))
 	})
 
-	log.Println("Server listening on :8080")
-	log.Println("Visit http://localhost:8080 to see file contents")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Println("Server listening on :" + port)
+	log.Printf("Visit http://localhost:%s to see file contents", port)
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
+		log.Fatal(err)
+	}
 }
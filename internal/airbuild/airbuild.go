@@ -0,0 +1,193 @@
+// Package airbuild builds Air from the air submodule at arbitrary refs and
+// keeps the binaries in a local cache keyed by commit.
+package airbuild
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DefaultRepo is where the air submodule is checked out.
+const DefaultRepo = "air"
+
+// Builder builds air binaries out of a git checkout of air.
+type Builder struct {
+	// Repo is the air checkout, normally the air submodule.
+	Repo string
+	// Cache holds one directory per commit with the air binary in it.
+	Cache string
+	// Remote is fetched from when a ref is not known locally.
+	Remote string
+}
+
+// New returns a Builder for repo that caches under the user cache directory.
+func New(repo string) (*Builder, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{
+		Repo:   repo,
+		Cache:  filepath.Join(cache, "air-repro", "air"),
+		Remote: "origin",
+	}, nil
+}
+
+// Binary is an air build for one ref.
+type Binary struct {
+	// Ref is what was asked for: a tag, branch, commit or pull/N.
+	Ref string
+	// Commit is the full hash Ref resolved to.
+	Commit string
+	// Path is the air executable.
+	Path string
+}
+
+// Short is the abbreviated commit hash.
+func (b Binary) Short() string {
+	if len(b.Commit) > 12 {
+		return b.Commit[:12]
+	}
+	return b.Commit
+}
+
+// Build returns the air binary for ref, building it when it is not cached.
+// Refs of the form pull/N are fetched from GitHub's pull request heads, the
+// way env-preload-test tests PR #856.
+func (b *Builder) Build(ref string) (Binary, error) {
+	if err := b.checkRepo(); err != nil {
+		return Binary{}, err
+	}
+	commit, err := b.Resolve(ref)
+	if err != nil {
+		return Binary{}, err
+	}
+	bin := Binary{Ref: ref, Commit: commit, Path: b.binPath(commit)}
+	if _, err := os.Stat(bin.Path); err == nil {
+		return bin, nil
+	}
+	if err := b.build(ref, commit, bin.Path); err != nil {
+		return Binary{}, fmt.Errorf("build air %s (%s): %w", ref, bin.Short(), err)
+	}
+	return bin, nil
+}
+
+func (b *Builder) binPath(commit string) string {
+	name := "air"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(b.Cache, commit, name)
+}
+
+func (b *Builder) checkRepo() error {
+	if _, err := os.Stat(filepath.Join(b.Repo, "go.mod")); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s is not an air checkout; run `git submodule update --init air`", b.Repo)
+		}
+		return err
+	}
+	return nil
+}
+
+// Resolve turns ref into a full commit hash, fetching from the remote when
+// the ref is not known locally.
+func (b *Builder) Resolve(ref string) (string, error) {
+	if pr, ok := strings.CutPrefix(ref, "pull/"); ok {
+		pr = strings.TrimSuffix(pr, "/head")
+		if _, err := b.git("fetch", "--quiet", b.Remote, "pull/"+pr+"/head"); err != nil {
+			return "", err
+		}
+		return b.git("rev-parse", "--verify", "FETCH_HEAD^{commit}")
+	}
+	if commit, err := b.git("rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+		return commit, nil
+	}
+	if _, err := b.git("fetch", "--quiet", "--tags", b.Remote); err != nil {
+		return "", err
+	}
+	for _, candidate := range []string{ref, b.Remote + "/" + ref} {
+		if commit, err := b.git("rev-parse", "--verify", "--quiet", candidate+"^{commit}"); err == nil {
+			return commit, nil
+		}
+	}
+	return "", fmt.Errorf("unknown air ref %q", ref)
+}
+
+// build checks commit out into a throwaway worktree and compiles it with the
+// same version stamping as air's Makefile.
+func (b *Builder) build(ref, commit, out string) error {
+	work, err := os.MkdirTemp("", "air-build-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+	tree := filepath.Join(work, "air")
+	if _, err := b.git("worktree", "add", "--detach", "--force", tree, commit); err != nil {
+		return err
+	}
+	defer b.git("worktree", "remove", "--force", tree)
+
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return err
+	}
+	tmp := out + ".tmp"
+	ldflags := fmt.Sprintf("-X main.airVersion=%s -X main.goVersion=%s",
+		ref+"@"+commit[:min(12, len(commit))], strings.TrimPrefix(runtime.Version(), "go"))
+	cmd := exec.Command("go", "build", "-trimpath", "-ldflags", ldflags, "-o", tmp, ".")
+	cmd.Dir = tree
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("go build: %w\n%s", err, out)
+	}
+	return os.Rename(tmp, out)
+}
+
+func (b *Builder) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", b.Repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Range lists the first-parent commits after from up to and including to,
+// oldest first. from must be an ancestor of to.
+func (b *Builder) Range(from, to string) ([]string, error) {
+	if !b.IsAncestor(from, to) {
+		return nil, fmt.Errorf("%s is not an ancestor of %s", from, to)
+	}
+	out, err := b.git("rev-list", "--reverse", "--first-parent", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// IsAncestor reports whether commit older is an ancestor of commit newer.
+func (b *Builder) IsAncestor(older, newer string) bool {
+	_, err := b.git("merge-base", "--is-ancestor", older, newer)
+	return err == nil
+}
+
+// Describe returns a one-line summary of commit: short hash, date and subject.
+func (b *Builder) Describe(commit string) string {
+	out, err := b.git("log", "-1", "--format=%h %cs %s", commit)
+	if err != nil {
+		return commit
+	}
+	return out
+}
@@ -0,0 +1,272 @@
+// Package airconfig checks an example's .air.toml against the keys each air
+// release accepts and against the files it points at.
+package airconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/air-verse/air-reproducible-example/internal/catalog"
+)
+
+// FileName is the air config looked up in every example directory.
+const FileName = ".air.toml"
+
+// Rules a finding can belong to. meta.toml lists the ones an example
+// reproduces on purpose in expect_lint.
+const (
+	RuleSyntax      = "syntax"
+	RuleDuplicate   = "duplicate-key"
+	RuleUnknown     = "unknown-key"
+	RuleUnsupported = "unsupported-key"
+	RuleUnreleased  = "unreleased-key"
+	RulePath        = "path"
+)
+
+// Finding is one problem in a config file.
+type Finding struct {
+	File    string
+	Line    int
+	Rule    string
+	Key     string
+	Message string
+	// Ref is the issue or pull request an unreleased key belongs to.
+	Ref int
+	// Expected is set by LintExample when the example's meta.toml says the
+	// finding is what it reproduces.
+	Expected bool
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("%s:%d: %s: %s", f.File, f.Line, f.Rule, f.Message)
+	if f.Expected {
+		s += " (expected)"
+	}
+	return s
+}
+
+// Lint checks the config at file for the air release target, which defaults
+// to Latest.
+func Lint(file, target string) ([]Finding, error) {
+	if target == "" {
+		target = Latest
+	}
+	want, err := parseVersion(target)
+	if err != nil {
+		return nil, err
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{file: file, lines: map[string]int{}}
+	l.keys(string(src), want, target)
+	l.paths(string(src))
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		return l.findings[i].Line < l.findings[j].Line
+	})
+	return l.findings, nil
+}
+
+type linter struct {
+	file     string
+	findings []Finding
+	// lines maps a key to the line of its first assignment.
+	lines      map[string]int
+	duplicates bool
+}
+
+func (l *linter) add(line int, rule, key, format string, args ...any) {
+	l.findings = append(l.findings, Finding{
+		File:    l.file,
+		Line:    line,
+		Rule:    rule,
+		Key:     key,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) keys(src string, want version, target string) {
+	keys, headers := scan(src)
+
+	tables := map[string]int{}
+	for _, h := range headers {
+		if h.array {
+			continue
+		}
+		if first, ok := tables[h.name]; ok {
+			l.add(h.line, RuleDuplicate, h.name, "table [%s] is already defined on line %d", h.name, first)
+			l.duplicates = true
+			continue
+		}
+		tables[h.name] = h.line
+	}
+
+	seen := map[string]int{}
+	for _, k := range keys {
+		id := k.scope + "\x00" + k.key
+		if first, ok := seen[id]; ok {
+			l.add(k.line, RuleDuplicate, k.key, "%s is already set on line %d; air refuses the whole file", k.key, first)
+			l.duplicates = true
+			continue
+		}
+		seen[id] = k.line
+		if _, ok := l.lines[k.key]; ok {
+			continue
+		}
+		l.lines[k.key] = k.line
+
+		if ref, ok := unreleasedKeys[k.key]; ok {
+			l.add(k.line, RuleUnreleased, k.key, "%s only exists on the air-verse/air#%d branch", k.key, ref)
+			l.findings[len(l.findings)-1].Ref = ref
+			continue
+		}
+		since := Since(k.key)
+		if since == "" {
+			l.add(k.line, RuleUnknown, k.key, "%s is not an air config key", k.key)
+			continue
+		}
+		if since == Baseline {
+			// Assumed to exist in older releases too; see Baseline.
+			continue
+		}
+		if v, _ := parseVersion(since); want.less(v) {
+			l.add(k.line, RuleUnsupported, k.key, "%s needs air %s or later, target is %s", k.key, since, target)
+		}
+	}
+}
+
+// outputFlag finds the -o target of a go build command.
+var outputFlag = regexp.MustCompile(`(?:^|\s)-o(?:=|\s+)("[^"]+"|'[^']+'|\S+)`)
+
+func (l *linter) paths(src string) {
+	var cfg map[string]any
+	if _, err := toml.Decode(src, &cfg); err != nil {
+		// A duplicate is already reported with both lines.
+		if !l.duplicates {
+			var perr toml.ParseError
+			line := 0
+			if errors.As(err, &perr) {
+				line = perr.Position.Line
+			}
+			l.add(line, RuleSyntax, "", "%v", err)
+		}
+		return
+	}
+	dir := filepath.Dir(l.file)
+	root := dir
+	if r := str(cfg, "root"); r != "" {
+		root = resolve(dir, r)
+		if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+			l.add(l.lines["root"], RulePath, "root", "root %q is not a directory", r)
+			return
+		}
+	}
+
+	if tmp := str(cfg, "tmp_dir"); tmp != "" && !filepath.IsAbs(tmp) {
+		if rel := clean(tmp); rel == ".." || strings.HasPrefix(rel, "../") {
+			l.add(l.lines["tmp_dir"], RulePath, "tmp_dir", "tmp_dir %q is outside root", tmp)
+		}
+	}
+
+	for _, key := range []string{"build.include_file", "build.include_dir", "env_files", "build.env_file"} {
+		for _, p := range strs(cfg, key) {
+			if _, err := os.Stat(resolve(root, p)); err != nil {
+				l.add(l.lines[key], RulePath, key, "%s entry %q does not exist under root", key, p)
+			}
+		}
+	}
+
+	m := outputFlag.FindStringSubmatch(str(cfg, "build.cmd"))
+	if m == nil {
+		return
+	}
+	out := clean(strings.Trim(m[1], `"'`))
+	if bin := str(cfg, "build.bin"); bin != "" && clean(bin) != out {
+		l.add(l.lines["build.bin"], RulePath, "build.bin", "bin %q is not what build.cmd writes (-o %s)", bin, m[1])
+	}
+	if ep := strs(cfg, "build.entrypoint"); len(ep) > 0 && clean(ep[0]) != out {
+		l.add(l.lines["build.entrypoint"], RulePath, "build.entrypoint", "entrypoint %q is not what build.cmd writes (-o %s)", ep[0], m[1])
+	}
+}
+
+// clean normalizes a config path for comparison; configs written for
+// Windows use backslashes.
+func clean(p string) string {
+	return path.Clean(strings.ReplaceAll(p, `\`, "/"))
+}
+
+func resolve(base, p string) string {
+	p = filepath.FromSlash(strings.ReplaceAll(p, `\`, "/"))
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(base, p)
+}
+
+// lookup follows a dotted key through decoded tables.
+func lookup(cfg map[string]any, key string) any {
+	var v any = cfg
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+func str(cfg map[string]any, key string) string {
+	s, _ := lookup(cfg, key).(string)
+	return s
+}
+
+// strs reads a string or an array of strings.
+func strs(cfg map[string]any, key string) []string {
+	switch v := lookup(cfg, key).(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var out []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// LintExample lints dir/.air.toml and marks the findings the example's
+// meta.toml expects: rules listed in expect_lint, and unreleased keys from
+// the issue or pull request the example is about.
+func LintExample(dir, target string) ([]Finding, error) {
+	findings, err := Lint(filepath.Join(dir, FileName), target)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := catalog.Load(dir)
+	if err != nil {
+		return findings, err
+	}
+	for i, f := range findings {
+		switch {
+		case slices.Contains(meta.ExpectLint, f.Rule):
+			findings[i].Expected = true
+		case f.Rule == RuleUnreleased && (f.Ref == meta.Issue || f.Ref == meta.PR):
+			findings[i].Expected = true
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,132 @@
+package airconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		target string
+		// files are created next to the config before linting.
+		files []string
+		// want are the findings as "line rule key".
+		want []string
+	}{
+		{
+			name: "clean config",
+			src: `root = "."
+tmp_dir = "tmp"
+[build]
+  cmd = "go build -o ./tmp/main ."
+  bin = "tmp/main"
+  include_file = ["myfile.txt"]`,
+			files: []string{"myfile.txt"},
+		},
+		{
+			name: "duplicate key and table",
+			src: `[build]
+  cmd = "go build"
+  cmd = "go build -race"
+[build]`,
+			want: []string{"3 duplicate-key build.cmd", "4 duplicate-key build"},
+		},
+		{
+			name: "same key in two array entries is no duplicate",
+			src: `[[build.rules]]
+  name = "a"
+[[build.rules]]
+  name = "b"`,
+		},
+		{
+			name: "unknown and unreleased keys",
+			src: `[build]
+  comand = "go build"
+  watch_mode = "poll"`,
+			want: []string{"2 unknown-key build.comand", "3 unreleased-key build.watch_mode"},
+		},
+		{
+			name:   "key newer than the target",
+			src:    "env_files = []\n[proxy]\n  app_start_timeout = 1000\n  enabled = true",
+			target: "v1.63.6",
+			want:   []string{"1 unsupported-key env_files"},
+		},
+		{
+			name:   "baseline keys are fine for older targets",
+			src:    "root = \".\"\n[build]\n  cmd = \"go build\"\n  entrypoint = [\"./main\"]",
+			target: "v1.40.0",
+			want:   []string{"4 unsupported-key build.entrypoint"},
+		},
+		{
+			name: "per-OS overrides",
+			src:  "[build.windows]\n  bin = \"tmp\\\\main.exe\"",
+		},
+		{
+			name: "inline table keys are checked",
+			src:  `proxy = { enabled = true, app_prot = 8080 }`,
+			want: []string{"1 unknown-key proxy.app_prot"},
+		},
+		{
+			name: "syntax error",
+			src:  "[build]\n  cmd = \"go build",
+			want: []string{"2 syntax "},
+		},
+		{
+			name: "paths",
+			src: `tmp_dir = "../tmp"
+[build]
+  cmd = "go build -o ./tmp/app ."
+  bin = "./tmp/main"
+  include_dir = ["missing"]`,
+			want: []string{
+				"1 path tmp_dir",
+				"4 path build.bin",
+				"5 path build.include_dir",
+			},
+		},
+		{
+			name: "root that is not a directory",
+			src:  `root = "nowhere"`,
+			want: []string{"1 path root"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, f), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			file := filepath.Join(dir, FileName)
+			if err := os.WriteFile(file, []byte(tt.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			findings, err := Lint(file, tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range findings {
+				got = append(got, fmt.Sprintf("%d %s %s", f.Line, f.Rule, f.Key))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("findings:\n got %q\nwant %q", got, tt.want)
+				for _, f := range findings {
+					t.Log(f)
+				}
+			}
+		})
+	}
+}
+
+func TestLintBadTarget(t *testing.T) {
+	if _, err := Lint(filepath.Join(t.TempDir(), FileName), "latest"); err == nil {
+		t.Error("Lint accepted target \"latest\"")
+	}
+}
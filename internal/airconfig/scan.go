@@ -0,0 +1,226 @@
+package airconfig
+
+import (
+	"strconv"
+	"strings"
+)
+
+// keyLine is one assignment in a TOML document.
+type keyLine struct {
+	// key is the full dotted key, table prefix included.
+	key string
+	// scope separates the entries of an array of tables, whose keys may
+	// repeat between entries.
+	scope string
+	line  int
+}
+
+// header is one [table] or [[array]] line.
+type header struct {
+	name  string
+	array bool
+	line  int
+}
+
+// scan lists the keys and table headers of src line by line. Unlike a TOML
+// decoder it does not stop at the first duplicate, so every duplicate can be
+// reported with the lines involved.
+func scan(src string) ([]keyLine, []header) {
+	var keys []keyLine
+	var headers []header
+	table, scope := "", ""
+	instances := map[string]int{}
+	var open valueState
+	for i, line := range strings.Split(src, "\n") {
+		n := i + 1
+		if open.pending() {
+			open.feed(line)
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(trimmed, "[["):
+			end := strings.Index(trimmed, "]]")
+			if end < 0 {
+				continue
+			}
+			table = normalizeKey(trimmed[2:end])
+			instances[table]++
+			scope = table + "#" + strconv.Itoa(instances[table])
+			headers = append(headers, header{name: table, array: true, line: n})
+			continue
+		case strings.HasPrefix(trimmed, "["):
+			end := strings.Index(trimmed, "]")
+			if end < 0 {
+				continue
+			}
+			table = normalizeKey(trimmed[1:end])
+			scope = table
+			headers = append(headers, header{name: table, line: n})
+			continue
+		}
+		eq := assignment(trimmed)
+		if eq < 0 {
+			continue
+		}
+		key := normalizeKey(trimmed[:eq])
+		if table != "" {
+			key = table + "." + key
+		}
+		if value := strings.TrimSpace(trimmed[eq+1:]); strings.HasPrefix(value, "{") {
+			// proxy = { enabled = true } sets proxy.enabled.
+			for _, k := range inlineKeys(key, value) {
+				keys = append(keys, keyLine{key: k, scope: scope, line: n})
+			}
+		} else {
+			keys = append(keys, keyLine{key: key, scope: scope, line: n})
+		}
+		open = valueState{}
+		open.feed(trimmed[eq+1:])
+	}
+	return keys, headers
+}
+
+// assignment returns the index of the '=' that ends the key, skipping
+// quoted keys, or -1.
+func assignment(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// inlineKeys lists the keys an inline table value sets under prefix,
+// following nested inline tables. TOML keeps an inline table on one line.
+func inlineKeys(prefix, value string) []string {
+	var keys []string
+	for _, entry := range inlineEntries(value) {
+		eq := assignment(entry)
+		if eq < 0 {
+			continue
+		}
+		key := prefix + "." + normalizeKey(entry[:eq])
+		if v := strings.TrimSpace(entry[eq+1:]); strings.HasPrefix(v, "{") {
+			keys = append(keys, inlineKeys(key, v)...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// inlineEntries splits the inline table value starts with at its top-level
+// commas, skipping strings, arrays and nested tables.
+func inlineEntries(value string) []string {
+	var entries []string
+	depth, start := 0, 1
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				return append(entries, value[start:i])
+			}
+		case ',':
+			if depth == 1 {
+				entries = append(entries, value[start:i])
+				start = i + 1
+			}
+		case '"', '\'':
+			for i++; i < len(value) && value[i] != c; i++ {
+				if c == '"' && value[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// SplitComment cuts a line of TOML at a # outside a string, so a rewrite
+// can leave the comment alone.
+func SplitComment(line string) (code, comment string) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i], line[i:]
+		}
+	}
+	return line, ""
+}
+
+// normalizeKey turns ` build . "cmd" ` into build.cmd.
+func normalizeKey(s string) string {
+	parts := strings.Split(s, ".")
+	for i, p := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(p), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+// valueState follows a value across lines: arrays and inline tables until
+// their brackets close, and multi-line strings until their closing quotes.
+type valueState struct {
+	depth     int
+	multiline string
+}
+
+func (v *valueState) pending() bool {
+	return v.depth > 0 || v.multiline != ""
+}
+
+func (v *valueState) feed(s string) {
+	for i := 0; i < len(s); i++ {
+		if v.multiline != "" {
+			if strings.HasPrefix(s[i:], v.multiline) {
+				i += len(v.multiline) - 1
+				v.multiline = ""
+			}
+			continue
+		}
+		switch c := s[i]; c {
+		case '#':
+			return
+		case '[', '{':
+			v.depth++
+		case ']', '}':
+			v.depth--
+		case '"', '\'':
+			if delim := strings.Repeat(string(c), 3); strings.HasPrefix(s[i:], delim) {
+				v.multiline = delim
+				i += 2
+				continue
+			}
+			// Skip a single-line string, honoring escapes in basic strings.
+			for i++; i < len(s) && s[i] != c; i++ {
+				if c == '"' && s[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+}
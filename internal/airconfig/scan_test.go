@@ -0,0 +1,153 @@
+package airconfig
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// keys are "line key", with " @scope" for the entries of an array
+		// of tables.
+		keys    []string
+		headers []string
+	}{
+		{
+			name: "tables and top-level keys",
+			src: `root = "."
+tmp_dir = "tmp"
+
+[build]
+  cmd = "go build -o ./tmp/main ."
+  bin = "tmp/main"`,
+			keys:    []string{"1 root", "2 tmp_dir", "5 build.cmd", "6 build.bin"},
+			headers: []string{"4 [build]"},
+		},
+		{
+			name:    "comments and header comments",
+			src:     "# cmd = \"no\"\n[build] # the build\n  # bin = \"no\"\n  cmd = \"go build # not a comment\" # a comment",
+			keys:    []string{"4 build.cmd"},
+			headers: []string{"2 [build]"},
+		},
+		{
+			name: "quoted and dotted keys",
+			src: `"root" = "."
+build."cmd" = "go build"
+[ "proxy" ]
+'app_port' = 8080
+"a=b" = 1`,
+			keys:    []string{"1 root", "2 build.cmd", "4 proxy.app_port", "5 proxy.a=b"},
+			headers: []string{"3 [proxy]"},
+		},
+		{
+			name: "multi-line arrays",
+			src: `[build]
+  exclude_dir = [
+    "tmp",
+    "vendor = [",
+  ]
+  delay = 1000`,
+			keys:    []string{"2 build.exclude_dir", "6 build.delay"},
+			headers: []string{"1 [build]"},
+		},
+		{
+			name: "multi-line strings",
+			src: `[build]
+  cmd = """
+go build \
+  -o tmp/main .
+bin = "inside the string"
+"""
+  bin = 'tmp/main'
+  full_bin = '''
+[log]
+'''
+  log = "x"`,
+			keys:    []string{"2 build.cmd", "7 build.bin", "8 build.full_bin", "11 build.log"},
+			headers: []string{"1 [build]"},
+		},
+		{
+			name: "multi-line string closed on its first line",
+			src:  "cmd = \"\"\"go build\"\"\"\nbin = \"tmp/main\"",
+			keys: []string{"1 cmd", "2 bin"},
+		},
+		{
+			name: "escaped quote in a string",
+			src:  "cmd = \"echo \\\" [\"\nbin = \"x\"",
+			keys: []string{"1 cmd", "2 bin"},
+		},
+		{
+			name: "inline tables",
+			src: `proxy = { enabled = true, app_port = 8080, proxy_port = 8081 }
+build = { cmd = "go build, then run", rules = { name = "x" }, exclude_dir = ["a", "b"] }
+misc = {}`,
+			keys: []string{
+				"1 proxy.enabled", "1 proxy.app_port", "1 proxy.proxy_port",
+				"2 build.cmd", "2 build.rules.name", "2 build.exclude_dir",
+			},
+		},
+		{
+			name: "arrays of tables",
+			src: `[[build.rules]]
+  name = "a"
+  cmd = "x"
+[[build.rules]]
+  name = "b"
+[log]
+  time = true`,
+			keys: []string{
+				"2 build.rules.name @build.rules#1", "3 build.rules.cmd @build.rules#1",
+				"5 build.rules.name @build.rules#2", "7 log.time",
+			},
+			headers: []string{"1 [[build.rules]]", "4 [[build.rules]]", "6 [log]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, headers := scan(tt.src)
+			var gotKeys, gotHeaders []string
+			for _, k := range keys {
+				s := fmt.Sprintf("%d %s", k.line, k.key)
+				if strings.Contains(k.scope, "#") {
+					s += " @" + k.scope
+				}
+				gotKeys = append(gotKeys, s)
+			}
+			for _, h := range headers {
+				if h.array {
+					gotHeaders = append(gotHeaders, fmt.Sprintf("%d [[%s]]", h.line, h.name))
+				} else {
+					gotHeaders = append(gotHeaders, fmt.Sprintf("%d [%s]", h.line, h.name))
+				}
+			}
+			if !slices.Equal(gotKeys, tt.keys) {
+				t.Errorf("keys:\n got %q\nwant %q", gotKeys, tt.keys)
+			}
+			if !slices.Equal(gotHeaders, tt.headers) {
+				t.Errorf("headers:\n got %q\nwant %q", gotHeaders, tt.headers)
+			}
+		})
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{`cmd = "x"`, 4},
+		{`"a=b" = 1`, 6},
+		{`'a=b'= 1`, 5},
+		{`no assignment`, -1},
+		{`"unterminated = 1`, -1},
+	}
+	for _, tt := range tests {
+		if got := assignment(tt.line); got != tt.want {
+			t.Errorf("assignment(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package airconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Baseline is the oldest air release the schema knows about: the first one
+// published as github.com/air-verse/air. Keys present there are assumed to
+// exist in every older release too.
+const Baseline = "v1.61.5"
+
+// Latest is the newest release in the schema and the default lint target.
+const Latest = "v1.67.4"
+
+// baseKeys are the keys of .air.toml in Baseline.
+var baseKeys = []string{
+	"root", "tmp_dir", "testdata_dir",
+	"build.cmd", "build.bin", "build.full_bin", "build.args_bin",
+	"build.pre_cmd", "build.post_cmd", "build.log",
+	"build.include_ext", "build.exclude_dir", "build.include_dir",
+	"build.exclude_file", "build.include_file", "build.exclude_regex",
+	"build.exclude_unchanged", "build.follow_symlink",
+	"build.poll", "build.poll_interval", "build.delay", "build.stop_on_error",
+	"build.send_interrupt", "build.kill_delay", "build.rerun", "build.rerun_delay",
+	"color.main", "color.watcher", "color.build", "color.runner", "color.app",
+	"log.time", "log.main_only", "log.silent",
+	"misc.clean_on_exit",
+	"screen.clear_on_rebuild", "screen.keep_scroll",
+	"proxy.enabled", "proxy.proxy_port", "proxy.app_port",
+}
+
+// addedKeys maps keys introduced after Baseline to the release that added
+// them, as read from runner/config.go of every tagged release.
+var addedKeys = map[string]string{
+	"build.entrypoint":                "v1.63.4",
+	"proxy.app_start_timeout":         "v1.63.6",
+	"env_files":                       "v1.64.1",
+	"build.ignore_dangerous_root_dir": "v1.64.1",
+	"misc.startup_banner":             "v1.65.1",
+	"color.mode":                      "v1.65.3",
+	"build.rules.name":                "v1.66.0",
+	"build.rules.cmd":                 "v1.66.0",
+	"build.rules.include_dir":         "v1.66.0",
+	"build.rules.include_ext":         "v1.66.0",
+	"build.rules.include_file":        "v1.66.0",
+	"build.rules.exclude_regex":       "v1.66.0",
+	"build.rules.delay":               "v1.66.0",
+}
+
+// overrideKeys are the build keys that build.windows, build.darwin and
+// build.linux may override; the per-OS tables arrived in v1.65.0.
+var overrideKeys = []string{"cmd", "bin", "full_bin", "args_bin", "entrypoint", "pre_cmd", "post_cmd"}
+
+// unreleasedKeys exist only on unmerged branches. The value is the
+// air-verse/air issue or pull request the branch belongs to.
+var unreleasedKeys = map[string]int{
+	"build.env_file":   856,
+	"build.watch_mode": 804,
+}
+
+func init() {
+	for _, goos := range []string{"windows", "darwin", "linux"} {
+		for _, k := range overrideKeys {
+			addedKeys["build."+goos+"."+k] = "v1.65.0"
+		}
+	}
+}
+
+// Since returns the first release that accepts key, Baseline for keys that
+// predate the schema, or "" when no release has it.
+func Since(key string) string {
+	for _, k := range baseKeys {
+		if k == key {
+			return Baseline
+		}
+	}
+	return addedKeys[key]
+}
+
+// version is a parsed vMAJOR.MINOR.PATCH.
+type version [3]int
+
+func parseVersion(s string) (version, error) {
+	var v version
+	parts := strings.SplitN(strings.TrimPrefix(s, "v"), ".", 3)
+	if len(parts) != 3 {
+		return v, fmt.Errorf("version %q is not vMAJOR.MINOR.PATCH", s)
+	}
+	for i, p := range parts {
+		// Drop pre-release and build suffixes: v1.62.0-rc1 counts as v1.62.0.
+		if j := strings.IndexAny(p, "-+"); j >= 0 {
+			p = p[:j]
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return v, fmt.Errorf("version %q is not vMAJOR.MINOR.PATCH", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func (v version) less(o version) bool {
+	for i := range v {
+		if v[i] != o[i] {
+			return v[i] < o[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package airconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Watch is the part of an .air.toml that decides which directories air
+// watches and which file events make it rebuild, with air's defaults for
+// the keys the file leaves out. Its methods follow runner/engine.go and
+// runner/util.go of air.
+type Watch struct {
+	// Root, TmpDir and TestdataDir are absolute.
+	Root        string
+	TmpDir      string
+	TestdataDir string
+
+	IncludeExt   []string
+	ExcludeDir   []string
+	IncludeDir   []string
+	ExcludeFile  []string
+	IncludeFile  []string
+	ExcludeRegex []*regexp.Regexp
+}
+
+// LoadWatch reads the watch settings of the config at file. A missing file
+// gives air's defaults rooted at its directory.
+func LoadWatch(file string) (*Watch, error) {
+	var cfg map[string]any
+	if _, err := toml.DecodeFile(file, &cfg); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	get := func(key, def string) string {
+		if s := str(cfg, key); s != "" {
+			return s
+		}
+		return def
+	}
+	list := func(key string, def ...string) []string {
+		if lookup(cfg, key) == nil {
+			return def
+		}
+		return strs(cfg, key)
+	}
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	w := &Watch{
+		Root:        resolve(dir, get("root", ".")),
+		IncludeExt:  list("build.include_ext", "go", "tpl", "tmpl", "html"),
+		ExcludeDir:  list("build.exclude_dir", "assets", "tmp", "vendor", "testdata"),
+		IncludeDir:  list("build.include_dir"),
+		ExcludeFile: list("build.exclude_file"),
+		IncludeFile: list("build.include_file"),
+	}
+	w.TmpDir = resolve(w.Root, get("tmp_dir", "tmp"))
+	w.TestdataDir = resolve(w.Root, get("testdata_dir", "testdata"))
+	for _, expr := range list("build.exclude_regex", "_test.go") {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		w.ExcludeRegex = append(w.ExcludeRegex, re)
+	}
+	return w, nil
+}
+
+// Rel is path relative to Root, with forward slashes.
+func (w *Watch) Rel(path string) string {
+	rel, err := filepath.Rel(w.Root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Dir reports whether air watches the directory at path and whether it
+// walks into it to look for more.
+func (w *Watch) Dir(path string) (watch, walk bool) {
+	path = filepath.Clean(path)
+	base := filepath.Base(path)
+	switch {
+	case path == w.TmpDir, path == w.TestdataDir:
+		return false, false
+	case len(path) > 1 && strings.HasPrefix(base, ".") && base != "..":
+		// Hidden directories like .git.
+		return false, false
+	case slices.Contains(w.ExcludeDir, w.Rel(path)):
+		return false, false
+	case len(w.IncludeDir) == 0 || !within(w.Root, path):
+		return true, true
+	}
+	for _, d := range w.IncludeDir {
+		d = resolve(w.Root, d)
+		if within(d, path) {
+			return true, true
+		}
+		if within(path, d) {
+			walk = true
+		}
+	}
+	return false, walk
+}
+
+// Watched reports whether air ends up watching the directory at path: air
+// walks every directory from Root down to it and watches it.
+func (w *Watch) Watched(path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if !within(w.Root, path) {
+		watch, _ := w.Dir(path)
+		return watch
+	}
+	rel, err := filepath.Rel(w.Root, path)
+	if err != nil {
+		return false
+	}
+	dir := w.Root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part != "." {
+			dir = filepath.Join(dir, part)
+		}
+		watch, walk := w.Dir(dir)
+		if dir == path {
+			return watch
+		}
+		if !walk {
+			return false
+		}
+	}
+	return false
+}
+
+// Triggers reports whether an event on the file at path makes air rebuild.
+// Only create, write, remove and rename events count; chmod never does.
+func (w *Watch) Triggers(path string) bool {
+	rel := w.Rel(path)
+	for _, pattern := range w.ExcludeFile {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return false
+		}
+	}
+	for _, re := range w.ExcludeRegex {
+		if re.MatchString(path) {
+			return false
+		}
+	}
+	if slices.Contains(w.IncludeFile, rel) {
+		return true
+	}
+	ext := filepath.Ext(path)
+	for _, e := range w.IncludeExt {
+		if e = strings.TrimSpace(e); e == "*" || ext == "."+e {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether target is base or inside it.
+func within(base, target string) bool {
+	rel, err := filepath.Rel(base, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
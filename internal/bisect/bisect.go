@@ -0,0 +1,86 @@
+// Package bisect finds the first commit in a range whose reproduction
+// verdict differs from the verdict at the start of the range.
+package bisect
+
+import (
+	"fmt"
+
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+)
+
+// Probe runs the reproduction against one commit. An error, or a verdict
+// that is neither the before nor the after one, makes the commit untestable and
+// it is skipped like `git bisect skip`.
+type Probe func(commit string) (runner.Verdict, error)
+
+// Step records one probe for the bisect log.
+type Step struct {
+	Commit  string
+	Verdict runner.Verdict
+	Err     error
+}
+
+// Result is the outcome of Search.
+type Result struct {
+	// First is the first commit with the after verdict. When untestable
+	// commits hide it, First is empty and Candidates lists the commits it
+	// could be.
+	First      string
+	Candidates []string
+	Steps      []Step
+}
+
+// Search bisects commits, ordered oldest first, where the commit just before
+// commits[0] has verdict before and the last commit has verdict after. It assumes
+// the verdict changes only once in the range.
+func Search(commits []string, before, after runner.Verdict, probe Probe, logf func(format string, args ...any)) (Result, error) {
+	if len(commits) == 0 {
+		return Result{}, fmt.Errorf("empty commit range")
+	}
+	if before == after {
+		return Result{}, fmt.Errorf("both ends of the range are %s; nothing to bisect", before)
+	}
+	var res Result
+	skipped := map[int]bool{}
+	// commits[lo] has the before verdict (lo == -1 is the start of the
+	// range), commits[hi] has the after one.
+	lo, hi := -1, len(commits)-1
+	for hi-lo > 1 {
+		mid, ok := pick(lo, hi, skipped)
+		if !ok {
+			res.Candidates = commits[lo+1 : hi+1]
+			return res, nil
+		}
+		v, err := probe(commits[mid])
+		res.Steps = append(res.Steps, Step{Commit: commits[mid], Verdict: v, Err: err})
+		switch {
+		case err != nil:
+			logf("skip %s: %v", commits[mid], err)
+			skipped[mid] = true
+		case v == before:
+			logf("%s %s (old)", commits[mid], v)
+			lo = mid
+		case v == after:
+			logf("%s %s (new)", commits[mid], v)
+			hi = mid
+		default:
+			logf("skip %s: verdict %s", commits[mid], v)
+			skipped[mid] = true
+		}
+	}
+	res.First = commits[hi]
+	return res, nil
+}
+
+// pick returns the untested index closest to the middle of (lo, hi).
+func pick(lo, hi int, skipped map[int]bool) (int, bool) {
+	mid := lo + (hi-lo)/2
+	for d := 0; d < hi-lo; d++ {
+		for _, i := range []int{mid - d, mid + d} {
+			if i > lo && i < hi && !skipped[i] {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
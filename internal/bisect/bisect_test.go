@@ -0,0 +1,171 @@
+package bisect
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+)
+
+// history is a fake range c0..c<n-1> whose verdict turns from Bug to Pass at
+// commit first.
+type history struct {
+	first int
+	// broken commits fail to build; odd ones end in a third verdict.
+	broken, odd []int
+}
+
+func (h history) probe(commit string) (runner.Verdict, error) {
+	var i int
+	fmt.Sscanf(commit, "c%d", &i)
+	switch {
+	case slices.Contains(h.broken, i):
+		return "", errors.New("build failed")
+	case slices.Contains(h.odd, i):
+		return runner.Error, nil
+	case i < h.first:
+		return runner.Bug, nil
+	}
+	return runner.Pass, nil
+}
+
+func commits(n int) []string {
+	var out []string
+	for i := range n {
+		out = append(out, fmt.Sprintf("c%d", i))
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits int
+		history history
+		// steps are the probed commits in order, with "!" for a skip.
+		steps      []string
+		first      string
+		candidates []string
+	}{
+		{
+			name:    "change in the middle",
+			commits: 8,
+			history: history{first: 5},
+			steps:   []string{"c3", "c5", "c4"},
+			first:   "c5",
+		},
+		{
+			name:    "change at the first commit",
+			commits: 8,
+			history: history{first: 0},
+			steps:   []string{"c3", "c1", "c0"},
+			first:   "c0",
+		},
+		{
+			name:    "change at the last commit is never probed",
+			commits: 4,
+			history: history{first: 3},
+			steps:   []string{"c1", "c2"},
+			first:   "c3",
+		},
+		{
+			name:    "single commit",
+			commits: 1,
+			history: history{first: 0},
+			first:   "c0",
+		},
+		{
+			name:    "build failure is skipped",
+			commits: 8,
+			history: history{first: 5, broken: []int{3}},
+			steps:   []string{"c3!", "c2", "c4", "c5"},
+			first:   "c5",
+		},
+		{
+			name:    "third verdict is skipped",
+			commits: 8,
+			history: history{first: 5, odd: []int{3}},
+			steps:   []string{"c3!", "c2", "c4", "c5"},
+			first:   "c5",
+		},
+		{
+			name:       "skips hide the change",
+			commits:    8,
+			history:    history{first: 5, broken: []int{4, 5}},
+			steps:      []string{"c3", "c5!", "c4!", "c6"},
+			candidates: []string{"c4", "c5", "c6"},
+		},
+		{
+			name:       "every commit skipped",
+			commits:    4,
+			history:    history{first: 2, broken: []int{0, 1, 2, 3}},
+			steps:      []string{"c1!", "c0!", "c2!"},
+			candidates: []string{"c0", "c1", "c2", "c3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Search(commits(tt.commits), runner.Bug, runner.Pass, tt.history.probe, t.Logf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var steps []string
+			for _, s := range res.Steps {
+				if s.Err != nil || s.Verdict == runner.Error {
+					steps = append(steps, s.Commit+"!")
+				} else {
+					steps = append(steps, s.Commit)
+				}
+			}
+			if !slices.Equal(steps, tt.steps) {
+				t.Errorf("steps = %q, want %q", steps, tt.steps)
+			}
+			if res.First != tt.first {
+				t.Errorf("First = %q, want %q", res.First, tt.first)
+			}
+			if !slices.Equal(res.Candidates, tt.candidates) {
+				t.Errorf("Candidates = %q, want %q", res.Candidates, tt.candidates)
+			}
+		})
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	probe := history{}.probe
+	if _, err := Search(nil, runner.Bug, runner.Pass, probe, t.Logf); err == nil {
+		t.Error("Search accepted an empty range")
+	}
+	if _, err := Search(commits(3), runner.Bug, runner.Bug, probe, t.Logf); err == nil {
+		t.Error("Search accepted the same verdict at both ends")
+	}
+}
+
+func TestPick(t *testing.T) {
+	tests := []struct {
+		lo, hi  int
+		skipped []int
+		want    int
+		ok      bool
+	}{
+		{-1, 7, nil, 3, true},
+		{-1, 8, nil, 3, true},
+		{3, 5, nil, 4, true},
+		{-1, 7, []int{3}, 2, true},
+		{-1, 7, []int{2, 3}, 4, true},
+		{-1, 7, []int{0, 1, 2, 3, 4}, 5, true},
+		{3, 5, []int{4}, 0, false},
+		{-1, 0, nil, 0, false},
+	}
+	for _, tt := range tests {
+		skipped := map[int]bool{}
+		for _, i := range tt.skipped {
+			skipped[i] = true
+		}
+		got, ok := pick(tt.lo, tt.hi, skipped)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("pick(%d, %d, %v) = %d, %v, want %d, %v", tt.lo, tt.hi, tt.skipped, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package catalog reads the meta.toml kept in every example directory and
+// renders the "Current samples" list of the root README from it.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// FileName is the metadata file every example directory must carry.
+const FileName = "meta.toml"
+
+// Status of the upstream problem an example reproduces.
+const (
+	// Open bugs still reproduce with the latest air release.
+	Open = "open"
+	// Pending bugs have a fix in an unmerged pull request.
+	Pending = "pending"
+	// Fixed bugs no longer reproduce; FixedIn names the first good release,
+	// checked against the example.
+	Fixed = "fixed"
+	// Reference examples are not tied to a bug and show a setup to try
+	// air against.
+	Reference = "reference"
+)
+
+var statuses = []string{Open, Pending, Fixed, Reference}
+
+// notExamples are the top-level directories that hold tooling rather than
+// reproductions.
+var notExamples = []string{"air", "cmd", "internal", "reports", "reprokit"}
+
+// Example is the metadata of one example directory.
+type Example struct {
+	// Summary is the catalog line: what the example shows, in a sentence.
+	Summary string `toml:"summary"`
+	// Issue is the air-verse/air issue number, 0 when there is none.
+	Issue int `toml:"issue"`
+	// PR is the air-verse/air pull request with the fix, if any.
+	PR int `toml:"pr"`
+	// Ports maps a role ("app", "proxy") to the TCP port it listens on.
+	Ports map[string]int `toml:"ports"`
+	// Platforms restricts the example to these GOOS values; empty means
+	// everywhere.
+	Platforms []string `toml:"platforms"`
+	// Status is one of open, pending, fixed or reference.
+	Status string `toml:"status"`
+	// FixedIn is the first air release without the bug.
+	FixedIn string `toml:"fixed_in"`
+	// ExpectLint lists the .air.toml lint rules the example trips on
+	// purpose, e.g. duplicate-key for a repro about a broken config.
+	ExpectLint []string `toml:"expect_lint"`
+
+	// Dir is the example directory the metadata was loaded from.
+	Dir string `toml:"-"`
+}
+
+// Name is the example directory name.
+func (e *Example) Name() string {
+	return filepath.Base(e.Dir)
+}
+
+// Load reads dir/meta.toml.
+func Load(dir string) (*Example, error) {
+	path := filepath.Join(dir, FileName)
+	var e Example
+	md, err := toml.DecodeFile(path, &e)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown keys %v", path, undecoded)
+	}
+	e.Dir = dir
+	if err := e.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &e, nil
+}
+
+func (e *Example) validate() error {
+	var errs []error
+	if e.Summary == "" {
+		errs = append(errs, errors.New("summary is empty"))
+	}
+	if !slices.Contains(statuses, e.Status) {
+		errs = append(errs, fmt.Errorf("status %q is not one of %s", e.Status, strings.Join(statuses, ", ")))
+	}
+	if e.Status == Pending && e.PR == 0 {
+		errs = append(errs, errors.New("pending status needs the pr with the fix"))
+	}
+	if e.Status == Fixed && e.FixedIn == "" {
+		errs = append(errs, errors.New("fixed status needs the fixed_in release it was checked against"))
+	}
+	if e.FixedIn != "" && e.Status != Fixed {
+		errs = append(errs, fmt.Errorf("fixed_in set on a %s example", e.Status))
+	}
+	for role, port := range e.Ports {
+		if port <= 0 || port > 65535 {
+			errs = append(errs, fmt.Errorf("ports.%s: %d is not a TCP port", role, port))
+		}
+	}
+	if e.Status != Reference && e.Issue == 0 && e.PR == 0 {
+		errs = append(errs, errors.New("issue or pr is required unless status is reference"))
+	}
+	return errors.Join(errs...)
+}
+
+// Dirs lists the example directories directly under root, sorted by name.
+func Dirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || strings.HasPrefix(name, ".") || slices.Contains(notExamples, name) {
+			continue
+		}
+		dirs = append(dirs, filepath.Join(root, name))
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// Discover loads the metadata of every example under root. A directory
+// without meta.toml is an error, and all of them are reported at once.
+func Discover(root string) ([]*Example, error) {
+	dirs, err := Dirs(root)
+	if err != nil {
+		return nil, err
+	}
+	var examples []*Example
+	var errs []error
+	for _, dir := range dirs {
+		e, err := Load(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("%s: no %s", filepath.Base(dir), FileName))
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		examples = append(examples, e)
+	}
+	return examples, errors.Join(errs...)
+}
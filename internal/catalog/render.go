@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Markers delimit the generated list in README.md.
+const (
+	BeginMarker = "<!-- BEGIN CATALOG: generated by `go run ./cmd/repro catalog` from each example's meta.toml -->"
+	EndMarker   = "<!-- END CATALOG -->"
+)
+
+var platformNames = map[string]string{
+	"windows": "Windows",
+	"darwin":  "macOS",
+	"linux":   "Linux",
+}
+
+// Line renders the catalog entry of one example.
+func (e *Example) Line() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "- `%s/`: ", e.Name())
+	if len(e.Platforms) > 0 {
+		names := make([]string, len(e.Platforms))
+		for i, p := range e.Platforms {
+			if names[i] = platformNames[p]; names[i] == "" {
+				names[i] = p
+			}
+		}
+		fmt.Fprintf(&b, "**%s-only:** ", strings.Join(names, "/"))
+	}
+	b.WriteString(strings.TrimSpace(e.Summary))
+	if len(e.Ports) > 0 {
+		fmt.Fprintf(&b, " %s.", e.ports())
+	}
+	if s := e.status(); s != "" {
+		b.WriteString(" " + s)
+	}
+	return b.String()
+}
+
+// ports lists the ports in numeric order: "App on `:3002`, proxy on `:3082`".
+func (e *Example) ports() string {
+	roles := make([]string, 0, len(e.Ports))
+	for role := range e.Ports {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		if pi, pj := e.Ports[roles[i]], e.Ports[roles[j]]; pi != pj {
+			return pi < pj
+		}
+		return roles[i] < roles[j]
+	})
+	parts := make([]string, len(roles))
+	for i, role := range roles {
+		parts[i] = fmt.Sprintf("%s on `:%d`", role, e.Ports[role])
+	}
+	s := strings.Join(parts, ", ")
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func (e *Example) status() string {
+	var ref string
+	switch {
+	case e.Issue != 0:
+		ref = fmt.Sprintf("Reproduces air-verse/air#%d", e.Issue)
+	case e.PR != 0:
+		ref = fmt.Sprintf("Tests air-verse/air#%d", e.PR)
+	default:
+		return ""
+	}
+	switch e.Status {
+	case Pending:
+		if e.Issue != 0 {
+			return fmt.Sprintf("%s, fix pending in air-verse/air#%d.", ref, e.PR)
+		}
+		return ref + ", not merged yet."
+	case Fixed:
+		if e.FixedIn != "" {
+			return fmt.Sprintf("%s, fixed in %s.", ref, e.FixedIn)
+		}
+		return ref + ", fixed."
+	}
+	return ref + "."
+}
+
+// Render returns the catalog list, one line per example.
+func Render(examples []*Example) string {
+	var b strings.Builder
+	for _, e := range examples {
+		b.WriteString(e.Line())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Update replaces the text between the catalog markers of readme with list.
+func Update(readme, list string) (string, error) {
+	begin := strings.Index(readme, BeginMarker)
+	end := strings.Index(readme, EndMarker)
+	if begin < 0 || end < 0 {
+		return "", errors.New("catalog markers not found")
+	}
+	if end < begin {
+		return "", errors.New("catalog end marker comes before the begin marker")
+	}
+	head := readme[:begin+len(BeginMarker)]
+	return head + "\n" + list + readme[end:], nil
+}
@@ -0,0 +1,211 @@
+// Package editsave writes a new version of a file the way a given editor or
+// tool saves it. Watchers see a different sequence of events for each, and
+// bugs like a double build or a missed change often depend on exactly that
+// sequence, so scenarios can replay an edit under every strategy.
+package editsave
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Strategy names how a file is saved.
+type Strategy string
+
+const (
+	// Vim, with its defaults on Unix: probe the directory with a "4913"
+	// file, rename the original to a "~" backup, write a new file, restore
+	// its mode and delete the backup.
+	Vim Strategy = "vim"
+	// VSCode opens the existing file, truncates it and writes the content.
+	VSCode Strategy = "vscode"
+	// JetBrains "safe write": write a ___jb_tmp___ file, rename the original
+	// to ___jb_old___, rename the temporary file over it, delete the old one.
+	JetBrains Strategy = "jetbrains"
+	// Gofmt saves in place, then rewrites the file the way gofmt -w does
+	// when formatting changes it: a backup file next to it, an overwrite
+	// followed by a truncate, then the backup is removed.
+	Gofmt Strategy = "gofmt"
+	// Git checkout deletes the file and creates it anew.
+	Git Strategy = "git"
+	// Remote stands in for a change made on the other side of a network
+	// share such as WSL2's /mnt/c (9P) or NFS: the content and mtime change
+	// but the local kernel sends no inotify event. See SaveRemote.
+	Remote Strategy = "remote"
+)
+
+// Strategies lists every strategy in a stable order.
+var Strategies = []Strategy{Vim, VSCode, JetBrains, Gofmt, Git, Remote}
+
+// Parse returns the strategy called name.
+func Parse(name string) (Strategy, error) {
+	for _, s := range Strategies {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	names := make([]string, len(Strategies))
+	for i, s := range Strategies {
+		names[i] = string(s)
+	}
+	return "", fmt.Errorf("unknown editor %q (want one of %s)", name, strings.Join(names, ", "))
+}
+
+// Save replaces the content of path with data using strategy s. The file
+// keeps its permissions; a missing file is created with 0644.
+func Save(path string, data []byte, s Strategy) error {
+	perm := fs.FileMode(0o644)
+	info, err := os.Stat(path)
+	exists := err == nil
+	switch {
+	case exists:
+		perm = info.Mode().Perm()
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	if s == Remote {
+		return SaveRemote(path, data)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	switch s {
+	case Vim:
+		return saveVim(path, data, perm, exists)
+	case VSCode:
+		return saveTruncate(path, data, perm)
+	case JetBrains:
+		return saveJetBrains(path, data, perm, exists)
+	case Gofmt:
+		return saveGofmt(path, data, perm)
+	case Git:
+		return saveGit(path, data, perm, exists)
+	}
+	return fmt.Errorf("unknown editor %q", s)
+}
+
+func saveVim(path string, data []byte, perm fs.FileMode, exists bool) error {
+	probe := filepath.Join(filepath.Dir(path), "4913")
+	if f, err := os.OpenFile(probe, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm); err == nil {
+		f.Close()
+		os.Remove(probe)
+	}
+	backup := path + "~"
+	if exists {
+		if err := os.Rename(path, backup); err != nil {
+			return err
+		}
+	}
+	if err := create(path, data, perm); err != nil {
+		return err
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		return err
+	}
+	if exists {
+		return os.Remove(backup)
+	}
+	return nil
+}
+
+func saveTruncate(path string, data []byte, perm fs.FileMode) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func saveJetBrains(path string, data []byte, perm fs.FileMode, exists bool) error {
+	tmp, old := path+"___jb_tmp___", path+"___jb_old___"
+	if err := create(tmp, data, perm); err != nil {
+		return err
+	}
+	if exists {
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if exists {
+		return os.Remove(old)
+	}
+	return nil
+}
+
+// gofmtBackup stands in for the random suffix gofmt gives its backup file,
+// so the event sequence is the same on every run.
+const gofmtBackup = ".5577006791947779410"
+
+func saveGofmt(path string, data []byte, perm fs.FileMode) error {
+	if err := saveTruncate(path, data, perm); err != nil {
+		return err
+	}
+	formatted, err := format.Source(data)
+	if err != nil || bytes.Equal(formatted, data) {
+		// gofmt leaves files it cannot parse or would not change alone.
+		return nil
+	}
+	backup := path + gofmtBackup
+	if err := create(backup, data, perm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	n, err := f.Write(formatted)
+	if err == nil {
+		err = f.Truncate(int64(n))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(backup)
+}
+
+func saveGit(path string, data []byte, perm fs.FileMode, exists bool) error {
+	if exists {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return create(path, data, perm)
+}
+
+// create writes a file that must not exist yet and syncs it, as editors do
+// before they rename anything over the original.
+func create(path string, data []byte, perm fs.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
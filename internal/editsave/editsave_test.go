@@ -0,0 +1,102 @@
+package editsave
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// local are the strategies that work on any file system.
+var local = []Strategy{Vim, VSCode, JetBrains, Gofmt, Git}
+
+func TestSave(t *testing.T) {
+	for _, s := range local {
+		t.Run(string(s)+"/existing", func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.go")
+			if err := os.WriteFile(path, []byte("package main\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			want := "package main\n\nfunc main() {}\n"
+			if err := Save(path, []byte(want), s); err != nil {
+				t.Fatal(err)
+			}
+			checkFile(t, path, want)
+			if info, err := os.Stat(path); err != nil {
+				t.Fatal(err)
+			} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+				t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+			}
+			checkDir(t, dir, "main.go")
+		})
+		t.Run(string(s)+"/missing", func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "sub", "new.txt")
+			if err := Save(path, []byte("hello\n"), s); err != nil {
+				t.Fatal(err)
+			}
+			checkFile(t, path, "hello\n")
+			checkDir(t, filepath.Join(dir, "sub"), "new.txt")
+		})
+	}
+}
+
+func TestSaveGofmtFormats(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(path, []byte("package main\nfunc  main( ) {}\n"), Gofmt); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, path, "package main\n\nfunc main() {}\n")
+	checkDir(t, dir, "main.go")
+
+	// A file gofmt cannot parse is saved as it is.
+	if err := Save(path, []byte("not go {"), Gofmt); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, path, "not go {")
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range Strategies {
+		if got, err := Parse(string(s)); err != nil || got != s {
+			t.Errorf("Parse(%q) = %q, %v", s, got, err)
+		}
+	}
+	if _, err := Parse("emacs"); err == nil {
+		t.Error("Parse accepted an unknown editor")
+	}
+}
+
+func checkFile(t *testing.T, path, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+}
+
+// checkDir fails when dir holds anything but names, e.g. a backup or
+// temporary file an editor forgot to remove.
+func checkDir(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if !slices.Equal(got, names) {
+		t.Errorf("files in %s = %q, want %q", dir, got, names)
+	}
+}
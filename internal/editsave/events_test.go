@@ -0,0 +1,171 @@
+package editsave
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/fstrace"
+)
+
+// quiet is how long a recording must stay unchanged to count as complete.
+const quiet = 300 * time.Millisecond
+
+func TestSaveEvents(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the expected sequences are inotify's")
+	}
+	const formatted = "package main\n\nfunc main() {}\n"
+	tests := []struct {
+		strategy Strategy
+		data     string
+		// saved is the content after the save when it differs from data.
+		saved string
+		// want are "OP name" with consecutive duplicates merged, as inotify
+		// merges them when the reader has not taken the first one yet.
+		want []string
+	}{
+		{
+			strategy: Vim,
+			data:     formatted,
+			want: []string{
+				"CREATE 4913", "REMOVE 4913",
+				"RENAME main.go", "CREATE main.go~",
+				"CREATE main.go", "WRITE main.go", "CHMOD main.go",
+				"REMOVE main.go~",
+			},
+		},
+		{
+			strategy: VSCode,
+			data:     formatted,
+			want:     []string{"WRITE main.go"},
+		},
+		{
+			strategy: JetBrains,
+			data:     formatted,
+			want: []string{
+				"CREATE main.go___jb_tmp___", "WRITE main.go___jb_tmp___",
+				"RENAME main.go", "CREATE main.go___jb_old___",
+				"RENAME main.go___jb_tmp___", "CREATE main.go",
+				"REMOVE main.go___jb_old___",
+			},
+		},
+		{
+			strategy: Gofmt,
+			data:     formatted,
+			want:     []string{"WRITE main.go"},
+		},
+		{
+			strategy: Gofmt,
+			data:     "package main\nfunc  main( ) {}\n",
+			saved:    formatted,
+			want: []string{
+				"WRITE main.go",
+				"CREATE main.go" + gofmtBackup, "WRITE main.go" + gofmtBackup,
+				"WRITE main.go",
+				"REMOVE main.go" + gofmtBackup,
+			},
+		},
+		{
+			strategy: Git,
+			data:     formatted,
+			want:     []string{"REMOVE main.go", "CREATE main.go", "WRITE main.go"},
+		},
+		{
+			strategy: Remote,
+			// A remote edit keeps the size of the file.
+			data: "package main\n\nfunc main() {}\n\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.go")
+			if err := os.WriteFile(path, []byte("package main\n\nfunc main() { }\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got := record(t, dir, func() error { return Save(path, []byte(tt.data), tt.strategy) })
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events:\n got %q\nwant %q", got, tt.want)
+			}
+			if tt.saved == "" {
+				tt.saved = tt.data
+			}
+			checkFile(t, path, tt.saved)
+		})
+	}
+}
+
+// record runs save while an fstrace recorder watches dir and returns the
+// events it caused.
+func record(t *testing.T, dir string, save func() error) []string {
+	t.Helper()
+	r, err := fstrace.NewRecorder(filepath.Join(dir, ".air.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, out := &syncBuffer{}, &syncBuffer{}
+	r.Log = log
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Record(ctx, out) }()
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(log.String(), "watching ."); {
+		if time.Now().After(deadline) {
+			t.Fatal("the recorder did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := save(); err != nil {
+		t.Fatal(err)
+	}
+	for last := ""; ; {
+		time.Sleep(quiet)
+		if s := out.String(); s != last {
+			last = s
+			continue
+		}
+		break
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	events, err := fstrace.Read(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range events {
+		s := e.Op + " " + e.Path
+		if len(got) == 0 || got[len(got)-1] != s {
+			got = append(got, s)
+		}
+	}
+	return got
+}
+
+// syncBuffer is a bytes.Buffer the recorder writes to while the test reads.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
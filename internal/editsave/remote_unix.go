@@ -0,0 +1,44 @@
+//go:build !windows
+
+package editsave
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// SaveRemote changes the content of an existing file without an inotify
+// event: it writes through a shared memory mapping, which updates the
+// file's mtime but, unlike write(2), is not reported to watchers. Only
+// polling notices the change, as on a 9P or NFS mount.
+//
+// A mapping cannot change the file size without a truncate, which would be
+// reported, so data is padded with newlines to the current size and must
+// not be longer.
+func SaveRemote(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := int(info.Size())
+	if len(data) > size {
+		return fmt.Errorf("%s: a remote edit cannot grow the file from %d to %d bytes", path, size, len(data))
+	}
+	if size == 0 {
+		return nil
+	}
+	m, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+	copy(m, data)
+	copy(m[len(data):], bytes.Repeat([]byte{'\n'}, size-len(data)))
+	return syscall.Munmap(m)
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+
+package editsave
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveRemote(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "same size", old: "greet v1\n", data: "greet v2\n", want: "greet v2\n"},
+		{name: "shorter is padded with newlines", old: "version v10\n", data: "version v9\n", want: "version v9\n\n"},
+		{name: "empty data blanks the file", old: "abc", data: "", want: "\n\n\n"},
+		{name: "empty file stays empty", old: "", data: "", want: ""},
+		{name: "longer is refused", old: "v1\n", data: "v1 and more\n", want: "v1\n", wantErr: true},
+		{name: "empty file cannot grow", old: "", data: "x", want: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "main.go")
+			if err := os.WriteFile(path, []byte(tt.old), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := SaveRemote(path, []byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SaveRemote error = %v, want error %v", err, tt.wantErr)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveRemoteMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := Save(path, []byte("x"), Remote); err == nil {
+		t.Error("Save with remote created a missing file")
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("the file exists after a failed remote save")
+	}
+}
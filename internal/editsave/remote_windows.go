@@ -0,0 +1,9 @@
+package editsave
+
+import "errors"
+
+// SaveRemote is not available on Windows, where no write escapes
+// ReadDirectoryChangesW.
+func SaveRemote(path string, data []byte) error {
+	return errors.New("remote edits are not supported on windows")
+}
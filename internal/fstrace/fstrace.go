@@ -0,0 +1,226 @@
+// Package fstrace records the filesystem events of an example the way air's
+// watcher sees them and replays a recording as file operations.
+//
+// A trace is JSON Lines, one Event per line. Traces make double-build and
+// missed-event bugs reproducible on another machine: replay the trace in
+// the example while air runs.
+package fstrace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/air-verse/air-reproducible-example/internal/airconfig"
+)
+
+// Event is one raw fsnotify event.
+type Event struct {
+	// Time is when the event was received, in nanoseconds since the Unix
+	// epoch.
+	Time int64 `json:"t"`
+	// Op is the fsnotify operation, e.g. "WRITE" or "CREATE|CHMOD".
+	Op string `json:"op"`
+	// Path is relative to the watched root, with forward slashes.
+	Path string `json:"path"`
+	Dir  bool   `json:"dir,omitempty"`
+	// Triggers is whether air's rules from .air.toml would rebuild on it.
+	Triggers bool `json:"triggers"`
+	// Mode is the file mode after a CHMOD.
+	Mode fs.FileMode `json:"mode,omitempty"`
+	// Data is the file content read right after a WRITE, unless it was
+	// larger than the recorder's limit; Size is its length either way.
+	Data []byte `json:"data,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// Recorder watches the directories air would watch.
+type Recorder struct {
+	rules *airconfig.Watch
+	// MaxData caps the content kept per WRITE event.
+	MaxData int64
+	// Log receives the directories being watched and watcher errors.
+	Log io.Writer
+}
+
+// NewRecorder loads the watch rules of the air config at cfg.
+func NewRecorder(cfg string) (*Recorder, error) {
+	rules, err := airconfig.LoadWatch(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{rules: rules, MaxData: 1 << 20, Log: io.Discard}, nil
+}
+
+// Record writes every event to out until ctx is done.
+func (r *Recorder) Record(ctx context.Context, out io.Writer) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := r.watchTree(w, r.rules.Root); err != nil {
+		return err
+	}
+	for _, f := range r.rules.IncludeFile {
+		if err := w.Add(filepath.Join(r.rules.Root, filepath.FromSlash(f))); err == nil {
+			fmt.Fprintf(r.Log, "watching %s\n", f)
+		}
+	}
+
+	enc := json.NewEncoder(out)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-w.Errors:
+			fmt.Fprintf(r.Log, "error: %v\n", err)
+		case ev := <-w.Events:
+			e := r.event(ev)
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+			if e.Dir && ev.Has(fsnotify.Create) {
+				// Air walks new directories too.
+				r.watchTree(w, ev.Name)
+			}
+		}
+	}
+}
+
+func (r *Recorder) event(ev fsnotify.Event) Event {
+	e := Event{
+		Time: time.Now().UnixNano(),
+		Op:   ev.Op.String(),
+		Path: r.rules.Rel(ev.Name),
+	}
+	info, err := os.Stat(ev.Name)
+	if err == nil {
+		e.Dir = info.IsDir()
+	}
+	valid := ev.Has(fsnotify.Create) || ev.Has(fsnotify.Write) || ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename)
+	e.Triggers = valid && !e.Dir && r.rules.Triggers(ev.Name)
+	if err != nil || e.Dir {
+		return e
+	}
+	if ev.Has(fsnotify.Chmod) {
+		e.Mode = info.Mode().Perm()
+	}
+	if ev.Has(fsnotify.Write) {
+		e.Size = info.Size()
+		if e.Size <= r.MaxData {
+			e.Data, _ = os.ReadFile(ev.Name)
+			e.Size = int64(len(e.Data))
+		}
+	}
+	return e
+}
+
+func (r *Recorder) watchTree(w *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		watch, walk := r.rules.Dir(path)
+		if watch {
+			if err := w.Add(path); err != nil {
+				return fmt.Errorf("watch %s: %w", path, err)
+			}
+			fmt.Fprintf(r.Log, "watching %s\n", r.rules.Rel(path))
+		}
+		if !walk {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// Read parses a trace.
+func Read(in io.Reader) ([]Event, error) {
+	var events []Event
+	sc := bufio.NewScanner(in)
+	sc.Buffer(nil, 64<<20)
+	for n := 1; sc.Scan(); n++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		events = append(events, e)
+	}
+	return events, sc.Err()
+}
+
+// renamePair is how long after a RENAME the CREATE of the new name may
+// come for the two to be replayed as one rename.
+const renamePair = 50 * time.Millisecond
+
+// Replay performs the events under root with their recorded spacing divided
+// by speed; speed 0 replays them back to back.
+//
+// CREATE makes an empty file or a directory, WRITE truncates the file and
+// writes the recorded data, REMOVE deletes, CHMOD sets the recorded mode.
+// fsnotify does not say where a file was renamed to, so a RENAME followed
+// by a CREATE within 50ms becomes a rename to that path and any other
+// RENAME a removal.
+func Replay(ctx context.Context, events []Event, root string, speed float64) error {
+	start := time.Now()
+	for i := 0; i < len(events); i++ {
+		e := events[i]
+		if speed > 0 {
+			offset := time.Duration(float64(e.Time-events[0].Time) / speed)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Until(start.Add(offset))):
+			}
+		}
+		path := filepath.Join(root, filepath.FromSlash(e.Path))
+		var err error
+		switch op := strings.Split(e.Op, "|"); {
+		case slices.Contains(op, "RENAME"):
+			if next := i + 1; next < len(events) && strings.Contains(events[next].Op, "CREATE") &&
+				time.Duration(events[next].Time-e.Time) <= renamePair {
+				err = os.Rename(path, filepath.Join(root, filepath.FromSlash(events[next].Path)))
+				i = next
+			} else {
+				err = os.RemoveAll(path)
+			}
+		case slices.Contains(op, "REMOVE"):
+			err = os.RemoveAll(path)
+		case slices.Contains(op, "CREATE") && e.Dir:
+			err = os.MkdirAll(path, 0o755)
+		case slices.Contains(op, "CREATE"):
+			var f *os.File
+			if f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644); err == nil {
+				err = f.Close()
+			}
+		case slices.Contains(op, "WRITE"):
+			if e.Data == nil && e.Size > 0 {
+				// Too large to record: rewrite what is there.
+				e.Data, err = os.ReadFile(path)
+			}
+			if err == nil {
+				err = os.WriteFile(path, e.Data, 0o644)
+			}
+		case slices.Contains(op, "CHMOD") && e.Mode != 0:
+			err = os.Chmod(path, e.Mode)
+		}
+		if err != nil {
+			return fmt.Errorf("event %d (%s %s): %w", i+1, e.Op, e.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package fstrace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	ms := int64(time.Millisecond)
+	tests := []struct {
+		name   string
+		before map[string]string
+		events []Event
+		// after maps every file under root to its content, with "/" for a
+		// directory.
+		after map[string]string
+	}{
+		{
+			name: "create, write and remove",
+			events: []Event{
+				{Op: "CREATE", Path: "sub", Dir: true},
+				{Op: "CREATE", Path: "sub/a.go"},
+				{Op: "WRITE", Path: "sub/a.go", Data: []byte("hi\n"), Size: 3},
+				{Op: "CREATE", Path: "b.go"},
+				{Op: "REMOVE", Path: "b.go"},
+			},
+			after: map[string]string{"sub": "/", "sub/a.go": "hi\n"},
+		},
+		{
+			name:   "write truncates",
+			before: map[string]string{"a.go": "a longer old content\n"},
+			events: []Event{{Op: "WRITE", Path: "a.go", Data: []byte("new\n"), Size: 4}},
+			after:  map[string]string{"a.go": "new\n"},
+		},
+		{
+			name:   "write too large to record rewrites the file",
+			before: map[string]string{"a.go": "kept\n"},
+			events: []Event{{Op: "WRITE", Path: "a.go", Size: 5}},
+			after:  map[string]string{"a.go": "kept\n"},
+		},
+		{
+			name:   "rename followed by create is one rename",
+			before: map[string]string{"main.go": "v1\n"},
+			events: []Event{
+				{Time: 0, Op: "RENAME", Path: "main.go"},
+				{Time: 10 * ms, Op: "CREATE", Path: "main.go~"},
+			},
+			after: map[string]string{"main.go~": "v1\n"},
+		},
+		{
+			name:   "rename without a create is a removal",
+			before: map[string]string{"main.go": "v1\n", "other.go": "x"},
+			events: []Event{
+				{Time: 0, Op: "RENAME", Path: "main.go"},
+				{Time: 100 * ms, Op: "CREATE", Path: "main.go~"},
+			},
+			after: map[string]string{"main.go~": "", "other.go": "x"},
+		},
+		{
+			name:   "vim save",
+			before: map[string]string{"main.go": "v1\n"},
+			events: []Event{
+				{Time: 0, Op: "CREATE", Path: "4913"},
+				{Time: 1 * ms, Op: "REMOVE", Path: "4913"},
+				{Time: 2 * ms, Op: "RENAME", Path: "main.go"},
+				{Time: 3 * ms, Op: "CREATE", Path: "main.go~"},
+				{Time: 4 * ms, Op: "CREATE", Path: "main.go"},
+				{Time: 5 * ms, Op: "WRITE", Path: "main.go", Data: []byte("v2\n"), Size: 3},
+				{Time: 6 * ms, Op: "CHMOD", Path: "main.go", Mode: 0o600},
+				{Time: 7 * ms, Op: "REMOVE", Path: "main.go~"},
+			},
+			after: map[string]string{"main.go": "v2\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.before {
+				if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := Replay(context.Background(), tt.events, root, 0); err != nil {
+				t.Fatal(err)
+			}
+			got := tree(t, root)
+			if len(got) != len(tt.after) {
+				t.Errorf("tree = %q, want %q", got, tt.after)
+			}
+			for name, want := range tt.after {
+				if got[name] != want {
+					t.Errorf("%s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayChmod(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix permissions")
+	}
+	root := t.TempDir()
+	path := filepath.Join(root, "run.sh")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	events := []Event{{Op: "CHMOD", Path: "run.sh", Mode: 0o755}}
+	if err := Replay(context.Background(), events, root, 0); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0o755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+}
+
+func TestReplayError(t *testing.T) {
+	events := []Event{
+		{Op: "CREATE", Path: "a.go"},
+		{Op: "WRITE", Path: "missing/b.go", Data: []byte("x")},
+	}
+	err := Replay(context.Background(), events, t.TempDir(), 0)
+	if err == nil || !strings.HasPrefix(err.Error(), "event 2 (WRITE missing/b.go)") {
+		t.Errorf("Replay error = %v, want one naming event 2", err)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Replay error = %v, want it to wrap fs.ErrNotExist", err)
+	}
+}
+
+func TestReplaySpacing(t *testing.T) {
+	root := t.TempDir()
+	events := []Event{
+		{Time: 0, Op: "CREATE", Path: "a.go"},
+		{Time: int64(200 * time.Millisecond), Op: "CREATE", Path: "b.go"},
+	}
+	start := time.Now()
+	if err := Replay(context.Background(), events, root, 2); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("replay at speed 2 took %s, want at least 100ms", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	events[1].Path = "c.go"
+	if err := Replay(ctx, events, root, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Replay after cancel = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "c.go")); err == nil {
+		t.Error("a canceled replay went on")
+	}
+}
+
+func TestRead(t *testing.T) {
+	in := `{"t":1,"op":"CREATE","path":"sub/a.go","triggers":true}
+
+{"t":2,"op":"WRITE","path":"sub/a.go","triggers":true,"data":"aGkK","size":3}
+`
+	events, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || string(events[1].Data) != "hi\n" || events[0].Path != "sub/a.go" {
+		t.Errorf("Read = %+v", events)
+	}
+	if _, err := Read(strings.NewReader("{}\nnot json\n")); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("Read error = %v, want one naming line 2", err)
+	}
+}
+
+// tree maps every file under root to its content and every directory to "/".
+func tree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	got := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			got[rel] = "/"
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		got[rel] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestRecord(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the expected sequence is inotify's")
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ".air.toml"), []byte("[build]\n  include_ext = [\"go\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRecorder(filepath.Join(root, ".air.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, out := &syncBuffer{}, &syncBuffer{}
+	r.Log = log
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Record(ctx, out) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+	waitFor(t, log, "watching .\n")
+
+	path := func(name string) string { return filepath.Join(root, name) }
+	// Each step waits for its events: the recorder reads a file's content
+	// and mode when it handles the event, after the next step would have
+	// renamed or removed it.
+	steps := []struct {
+		do   func() error
+		want []string
+	}{
+		{
+			func() error { return os.WriteFile(path("a.go"), []byte("v1\n"), 0o644) },
+			[]string{"CREATE a.go triggers", `WRITE a.go triggers "v1\n"`},
+		},
+		{
+			func() error { return os.WriteFile(path("notes.txt"), []byte("x"), 0o644) },
+			[]string{"CREATE notes.txt", `WRITE notes.txt "x"`},
+		},
+		{
+			func() error { return os.Rename(path("a.go"), path("b.go")) },
+			[]string{"RENAME a.go triggers", "CREATE b.go triggers"},
+		},
+		{
+			func() error { return os.Chmod(path("b.go"), 0o600) },
+			[]string{"CHMOD b.go mode=-rw-------"},
+		},
+		{
+			func() error { return os.Remove(path("b.go")) },
+			[]string{"REMOVE b.go triggers"},
+		},
+		{
+			func() error { return os.Mkdir(path("sub"), 0o755) },
+			[]string{"CREATE sub dir"},
+		},
+		{
+			// Air watches a new directory once it sees it; so does the
+			// recorder.
+			func() error {
+				waitFor(t, log, "watching sub\n")
+				return os.WriteFile(path("sub/c.go"), nil, 0o644)
+			},
+			[]string{"CREATE sub/c.go triggers"},
+		},
+	}
+	var want []string
+	for i, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, step.want...)
+		got := recorded(t, out, len(want))
+		if !slices.Equal(got, want) {
+			t.Fatalf("after step %d:\n got %q\nwant %q", i+1, got, want)
+		}
+	}
+}
+
+// recorded waits until out holds n events, or 5s, and describes them.
+func recorded(t *testing.T, out *syncBuffer, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events, err := Read(strings.NewReader(out.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) < n && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		var got []string
+		for _, e := range events {
+			s := e.Op + " " + e.Path
+			if e.Dir {
+				s += " dir"
+			}
+			if e.Triggers {
+				s += " triggers"
+			}
+			if e.Mode != 0 {
+				s += " mode=" + e.Mode.String()
+			}
+			if e.Data != nil {
+				s += " " + strconv.Quote(string(e.Data))
+			}
+			got = append(got, s)
+		}
+		return got
+	}
+}
+
+// syncBuffer is a bytes.Buffer the recorder writes to while the test reads.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, b *syncBuffer, s string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(b.String(), s); {
+		if time.Now().After(deadline) {
+			t.Fatalf("no %q in %q", s, b.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,182 @@
+// Package ports hands out free TCP ports to examples so that several of them
+// can run at once, and finds the examples whose default ports collide.
+//
+// Every example app reads its listen port from $PORT and, when it sits behind
+// Air's proxy, the proxy port from $PROXY_PORT, falling back to the port
+// declared in its meta.toml.
+package ports
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/air-verse/air-reproducible-example/internal/airconfig"
+	"github.com/air-verse/air-reproducible-example/internal/catalog"
+)
+
+// Env is the environment variable an example reads the port for role from:
+// PORT for the app, <ROLE>_PORT for anything else.
+func Env(role string) string {
+	if role == "app" {
+		return "PORT"
+	}
+	return strings.ToUpper(role) + "_PORT"
+}
+
+var (
+	mu sync.Mutex
+	// leased are the ports handed out and not released yet, so two
+	// examples started at the same time never get the same one.
+	leased = map[int]bool{}
+)
+
+// Lease reserves a free port for every role. The caller must Release them.
+func Lease(roles []string) (map[string]int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	got := map[string]int{}
+	for _, role := range roles {
+		p, err := free()
+		if err != nil {
+			for _, p := range got {
+				delete(leased, p)
+			}
+			return nil, fmt.Errorf("lease port for %s: %w", role, err)
+		}
+		leased[p] = true
+		got[role] = p
+	}
+	return got, nil
+}
+
+// Release returns leased ports.
+func Release(ports map[string]int) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range ports {
+		delete(leased, p)
+	}
+}
+
+// free asks the kernel for an unused port that is not leased already.
+func free() (int, error) {
+	for range 20 {
+		l, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return 0, err
+		}
+		p := l.Addr().(*net.TCPAddr).Port
+		l.Close()
+		if !leased[p] {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port")
+}
+
+// Busy reports whether something on this machine listens on port.
+func Busy(port int) bool {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return true
+	}
+	l.Close()
+	return false
+}
+
+// Vars maps the environment variable of every role to its port.
+func Vars(ports map[string]int) map[string]string {
+	vars := map[string]string{}
+	for role, p := range ports {
+		vars[Env(role)] = strconv.Itoa(p)
+	}
+	return vars
+}
+
+// Environ renders Vars as KEY=value pairs, sorted.
+func Environ(ports map[string]int) []string {
+	var env []string
+	for k, v := range Vars(ports) {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// The port settings of the proxy table can be written three ways:
+//
+//	[proxy]
+//	  app_port = 8080
+//
+//	proxy.app_port = 8080
+//
+//	proxy = { app_port = 8080, proxy_port = 8081 }
+var (
+	tableHeader = regexp.MustCompile(`^\s*\[\[?\s*([^\]]*?)\s*\]`)
+	portKey     = regexp.MustCompile(`^(\s*"?(app_port|proxy_port)"?\s*=\s*)(\d+)`)
+	dottedKey   = regexp.MustCompile(`^(\s*"?proxy"?\s*\.\s*"?(app_port|proxy_port)"?\s*=\s*)(\d+)`)
+	inlineTable = regexp.MustCompile(`^\s*"?proxy"?\s*=\s*\{`)
+	inlineKey   = regexp.MustCompile(`([{,]\s*"?(app_port|proxy_port)"?\s*=\s*)(\d+)`)
+)
+
+// RewriteConfig points the proxy table of an .air.toml at the leased
+// ports. It edits the text in place so that everything else, including the
+// mistakes some examples reproduce, stays byte for byte the same. Comments
+// and the port keys of other tables are left alone.
+func RewriteConfig(src []byte, ports map[string]int) []byte {
+	lines := strings.SplitAfter(string(src), "\n")
+	table := ""
+	for i, line := range lines {
+		code, comment := airconfig.SplitComment(line)
+		if m := tableHeader.FindStringSubmatch(code); m != nil {
+			table = strings.ReplaceAll(strings.ReplaceAll(m[1], `"`, ""), " ", "")
+			continue
+		}
+		var key *regexp.Regexp
+		switch {
+		case table == "proxy":
+			key = portKey
+		case table != "":
+			continue
+		case inlineTable.MatchString(code):
+			key = inlineKey
+		default:
+			key = dottedKey
+		}
+		lines[i] = key.ReplaceAllStringFunc(code, func(m string) string {
+			sub := key.FindStringSubmatch(m)
+			p, ok := ports[strings.TrimSuffix(sub[2], "_port")]
+			if !ok {
+				return m
+			}
+			return sub[1] + strconv.Itoa(p)
+		}) + comment
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// Collisions maps every default port declared by more than one example to
+// those examples' names.
+func Collisions(examples []*catalog.Example) map[int][]string {
+	users := map[int][]string{}
+	for _, e := range examples {
+		seen := map[int]bool{}
+		for _, p := range e.Ports {
+			if !seen[p] {
+				seen[p] = true
+				users[p] = append(users[p], e.Name())
+			}
+		}
+	}
+	for p, names := range users {
+		if len(names) < 2 {
+			delete(users, p)
+		}
+	}
+	return users
+}
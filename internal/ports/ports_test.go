@@ -0,0 +1,73 @@
+package ports
+
+import "testing"
+
+func TestRewriteConfig(t *testing.T) {
+	leased := map[string]int{"app": 41000, "proxy": 41001}
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "proxy table",
+			src:  "[build]\n  cmd = \"go build\"\n\n[proxy]\n  enabled = true\n  app_port = 8080\n  proxy_port = 8081\n",
+			want: "[build]\n  cmd = \"go build\"\n\n[proxy]\n  enabled = true\n  app_port = 41000\n  proxy_port = 41001\n",
+		},
+		{
+			name: "commented-out keys stay",
+			src:  "[proxy]\n  # app_port = 8080\n#proxy_port = 8081\n  app_port = 8090\n",
+			want: "[proxy]\n  # app_port = 8080\n#proxy_port = 8081\n  app_port = 41000\n",
+		},
+		{
+			name: "inline comments stay",
+			src:  "[proxy]\n  app_port = 8080 # app_port = 1\n  proxy_port = 8081# was 9000, proxy_port = 2\n",
+			want: "[proxy]\n  app_port = 41000 # app_port = 1\n  proxy_port = 41001# was 9000, proxy_port = 2\n",
+		},
+		{
+			name: "dotted keys",
+			src:  "proxy.enabled = true\nproxy.app_port = 8080\n\"proxy\" . \"proxy_port\"=8081\n[build]\n  cmd = \"go build\"\n",
+			want: "proxy.enabled = true\nproxy.app_port = 41000\n\"proxy\" . \"proxy_port\"=41001\n[build]\n  cmd = \"go build\"\n",
+		},
+		{
+			name: "inline table",
+			src:  "proxy = { enabled = true, app_port = 8080, proxy_port = 8081 } # app_port = 3\n",
+			want: "proxy = { enabled = true, app_port = 41000, proxy_port = 41001 } # app_port = 3\n",
+		},
+		{
+			name: "quoted table name",
+			src:  "[ \"proxy\" ]\n  \"app_port\" = 8080\n",
+			want: "[ \"proxy\" ]\n  \"app_port\" = 41000\n",
+		},
+		{
+			name: "other tables keep their ports",
+			src:  "app_port = 1\n[build]\n  app_port = 8080\n[proxy.extra]\n  proxy_port = 8081\n",
+			want: "app_port = 1\n[build]\n  app_port = 8080\n[proxy.extra]\n  proxy_port = 8081\n",
+		},
+		{
+			name: "no trailing newline",
+			src:  "[proxy]\napp_port = 8080",
+			want: "[proxy]\napp_port = 41000",
+		},
+		{
+			name: "hash in a string is no comment",
+			src:  "[proxy]\n  note = \"# \\\" app_port = 1\" \n  app_port = 8080\n",
+			want: "[proxy]\n  note = \"# \\\" app_port = 1\" \n  app_port = 41000\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(RewriteConfig([]byte(tt.src), leased)); got != tt.want {
+				t.Errorf("RewriteConfig:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteConfigUnleasedRole(t *testing.T) {
+	src := "[proxy]\n  app_port = 8080\n  proxy_port = 8081\n"
+	want := "[proxy]\n  app_port = 41000\n  proxy_port = 8081\n"
+	if got := string(RewriteConfig([]byte(src), map[string]int{"app": 41000})); got != want {
+		t.Errorf("RewriteConfig:\n got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,210 @@
+package refproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Probe is one request sent to an example directly and through each proxy.
+type Probe struct {
+	Path string
+	// Header is added to the request, e.g. the Accept-Encoding of a
+	// browser.
+	Header map[string]string
+	// Events makes the probe read an event stream: the body is the first
+	// Events events, or what arrived within Within.
+	Events int
+	// Within bounds the whole request; 10s when zero.
+	Within time.Duration
+	// Mask matches the parts of the body that differ between two requests,
+	// like a request counter. They are replaced before comparing.
+	Mask string
+}
+
+// Response is what a client got for a Probe.
+type Response struct {
+	Status int
+	Header http.Header
+	// Body is decoded per Content-Encoding, masked, and stripped of
+	// injected reload scripts.
+	Body []byte
+	// Scripts is the number of reload scripts that were injected.
+	Scripts int
+	// Length is the size of the body as received, before decoding.
+	Length int
+	// DecodeErr is why the body did not decode per its Content-Encoding;
+	// Body then holds the bytes as received.
+	DecodeErr error
+	// Elapsed is the time until the body was complete.
+	Elapsed time.Duration
+}
+
+// comparedHeaders are the response headers a proxy must not change.
+var comparedHeaders = []string{"Content-Type", "Cache-Control", "Vary"}
+
+// client sends probes without asking for, or decoding, compression on its
+// own: probes set Accept-Encoding themselves.
+var client = &http.Client{
+	Transport: &http.Transport{DisableCompression: true},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Fetch sends p to base, e.g. "http://localhost:3001".
+func Fetch(base string, p Probe) (*Response, error) {
+	within := p.Within
+	if within == 0 {
+		within = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), within)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+p.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range p.Header {
+		req.Header.Set(k, v)
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var raw []byte
+	if p.Events > 0 {
+		raw, err = readEvents(resp.Body, p.Events)
+		// Running out of time, or a stream that ends early, is an answer:
+		// the body has fewer events than asked for.
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = nil
+		}
+	} else {
+		raw, err = io.ReadAll(resp.Body)
+		// A body shorter than its Content-Length is an answer too, one
+		// Consistency reports.
+		if errors.Is(err, io.ErrUnexpectedEOF) && resp.ContentLength > 0 {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GET %s%s: %w", base, p.Path, err)
+	}
+	r := &Response{Status: resp.StatusCode, Header: resp.Header, Length: len(raw), Elapsed: time.Since(start)}
+	body, err := Decode(raw, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		// Keep the bytes; the diff shows they are not what was expected.
+		body, r.DecodeErr = raw, err
+	}
+	body, r.Scripts = Strip(body)
+	if p.Mask != "" {
+		body = regexp.MustCompile(p.Mask).ReplaceAll(body, []byte("<masked>"))
+	}
+	r.Body = body
+	return r, nil
+}
+
+// readEvents reads until n events, each ended by a blank line, arrived.
+func readEvents(body io.Reader, n int) ([]byte, error) {
+	var got []byte
+	buf := make([]byte, 4096)
+	for bytes.Count(got, []byte("\n\n")) < n {
+		m, err := body.Read(buf)
+		got = append(got, buf[:m]...)
+		if err != nil {
+			return got, err
+		}
+	}
+	return got, nil
+}
+
+// scriptTag matches one script element; the shortest match, so it never
+// spans two of them.
+var scriptTag = regexp.MustCompile(`(?s)<script>.*?</script>`)
+
+// marker is in every reload script, Air's and Script: both point at
+// /__air_internal/.
+const marker = "__air_internal"
+
+// Strip removes the injected reload scripts from page and counts them.
+func Strip(page []byte) ([]byte, int) {
+	n := 0
+	out := scriptTag.ReplaceAllFunc(page, func(m []byte) []byte {
+		if !bytes.Contains(m, []byte(marker)) {
+			return m
+		}
+		n++
+		return nil
+	})
+	return out, n
+}
+
+// Diff lists how got differs from want: the status, the headers a proxy
+// must keep, and the body.
+func Diff(want, got *Response) []string {
+	var diffs []string
+	if want.Status != got.Status {
+		diffs = append(diffs, fmt.Sprintf("status %d, want %d", got.Status, want.Status))
+	}
+	for _, h := range comparedHeaders {
+		if w, g := want.Header.Get(h), got.Header.Get(h); w != g {
+			diffs = append(diffs, fmt.Sprintf("%s %q, want %q", h, g, w))
+		}
+	}
+	if !bytes.Equal(want.Body, got.Body) {
+		diffs = append(diffs, bodyDiff(want.Body, got.Body))
+	}
+	return diffs
+}
+
+// magics are the leading bytes of the codings that have them.
+var magics = map[string][]byte{
+	"gzip": {0x1f, 0x8b},
+	"zstd": {0x28, 0xb5, 0x2f, 0xfd},
+}
+
+// Consistency lists where the Content-Length and Content-Encoding headers
+// of r do not describe its body: a length that is not the size received,
+// an encoding the body does not decode with, or a body still compressed
+// after decoding, as when a proxy drops one coding of several from the
+// header but not from the body. r must be a whole body, not an event
+// stream cut short.
+func Consistency(r *Response) []string {
+	var diffs []string
+	if cl := r.Header.Get("Content-Length"); cl != "" && cl != strconv.Itoa(r.Length) {
+		diffs = append(diffs, fmt.Sprintf("Content-Length %s, but the body is %d bytes", cl, r.Length))
+	}
+	enc := r.Header.Get("Content-Encoding")
+	if r.DecodeErr != nil {
+		diffs = append(diffs, fmt.Sprintf("Content-Encoding %q, but the body does not decode: %v", enc, r.DecodeErr))
+		return diffs
+	}
+	for name, magic := range magics {
+		if bytes.HasPrefix(r.Body, magic) {
+			diffs = append(diffs, fmt.Sprintf("Content-Encoding %q, but the body is still %s-compressed", enc, name))
+		}
+	}
+	return diffs
+}
+
+// bodyDiff describes where two bodies start to differ.
+func bodyDiff(want, got []byte) string {
+	i := 0
+	for i < len(want) && i < len(got) && want[i] == got[i] {
+		i++
+	}
+	excerpt := func(b []byte) string {
+		end := min(i+40, len(b))
+		return string(b[i:end])
+	}
+	return fmt.Sprintf("body differs at byte %d of %d (want %d): %q, want %q", i, len(got), len(want), excerpt(got), excerpt(want))
+}
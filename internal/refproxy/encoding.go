@@ -0,0 +1,75 @@
+package refproxy
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Decode undoes the Content-Encoding header value enc on body. Codings are
+// listed in the order they were applied, so they are undone from the last
+// one back. An empty enc or "identity" returns body unchanged.
+func Decode(body []byte, enc string) ([]byte, error) {
+	codings := Codings(enc)
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		body, err = decode(body, codings[i])
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", codings[i], err)
+		}
+	}
+	return body, nil
+}
+
+// Codings splits a Content-Encoding header value into its codings,
+// lowercased and without "identity".
+func Codings(enc string) []string {
+	var out []string
+	for _, c := range strings.Split(enc, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c != "" && c != "identity" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func decode(body []byte, coding string) ([]byte, error) {
+	var r io.Reader
+	switch coding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case "deflate":
+		// HTTP's deflate is zlib-wrapped, but some servers send raw
+		// deflate, as browsers also accept.
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			r = flate.NewReader(bytes.NewReader(body))
+		} else {
+			r = zr
+		}
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unsupported coding %q", coding)
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,281 @@
+// Package refproxy is a stand-in for Air's live-reload proxy that behaves
+// the way the proxy examples' READMEs say Air's should:
+//
+//   - the reload script is injected into every HTML page, whatever its
+//     Content-Encoding (gzip, deflate, br, zstd or several of them),
+//   - every other body is forwarded as it arrives, one flush per read, so
+//     Server-Sent Events are neither batched nor delayed,
+//   - /__air_internal/sse is the reload event stream, fed by Reload,
+//   - upgrade requests, like WebSockets, reach the app with their Upgrade
+//     header and are tunnelled both ways,
+//   - requests retry until the app accepts connections again, for as long
+//     as StartTimeout allows.
+//
+// Put an example behind it and behind Air's proxy, and the difference
+// between the two is the bug (see proxy_diff_test.go).
+package refproxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamPath is the reload event stream, the same path Air serves it on.
+const StreamPath = "/__air_internal/sse"
+
+// Script is injected into HTML pages. Strip finds it by its /__air_internal/
+// URL, like Air's own script.
+const Script = `<script>new EventSource("` + StreamPath + `").addEventListener("reload", () => location.reload());</script>`
+
+// DefaultStartTimeout is Air's default proxy.app_start_timeout.
+const DefaultStartTimeout = 5 * time.Second
+
+// hopByHop are the connection-specific headers of RFC 9110 section 7.6.1,
+// which a proxy consumes instead of forwarding.
+var hopByHop = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Proxy forwards to the app on AppPort. The zero value is not usable; use
+// New.
+type Proxy struct {
+	// AppPort is the port of the app on localhost.
+	AppPort int
+	// StartTimeout bounds how long a request retries while the app does
+	// not accept connections; DefaultStartTimeout when zero.
+	StartTimeout time.Duration
+
+	client *http.Client
+
+	mu   sync.Mutex
+	subs map[chan string]struct{}
+}
+
+// New returns a proxy for the app on appPort.
+func New(appPort int) *Proxy {
+	return &Proxy{
+		AppPort: appPort,
+		client: &http.Client{
+			// The browser's Accept-Encoding goes to the app unchanged, and
+			// what comes back is decoded only to inject the script.
+			Transport: &http.Transport{DisableCompression: true},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		subs: map[chan string]struct{}{},
+	}
+}
+
+// Reload sends a reload event to every open event stream.
+func (p *Proxy) Reload() {
+	p.broadcast("event: reload\ndata: null\n\n")
+}
+
+// Subscribers is the number of open event streams.
+func (p *Proxy) Subscribers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.subs)
+}
+
+func (p *Proxy) broadcast(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ch := range p.subs {
+		select {
+		case ch <- msg:
+		default:
+			// A stream that stopped reading misses the event rather
+			// than stalling the others.
+		}
+	}
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == StreamPath {
+		p.stream(w, r)
+		return
+	}
+	if upgrade(r) {
+		p.tunnel(w, r)
+		return
+	}
+	p.forward(w, r)
+}
+
+// stream serves the reload event stream until the client goes away.
+func (p *Proxy) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan string, 4)
+	p.mu.Lock()
+	p.subs[ch] = struct{}{}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.subs, ch)
+		p.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case msg := <-ch:
+			if _, err := io.WriteString(w, msg); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// forward passes r to the app, retrying until it accepts connections, and
+// copies the response back.
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request) {
+	// Read the body once so every retry can send it again.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "refproxy: read request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	timeout := p.StartTimeout
+	if timeout == 0 {
+		timeout = DefaultStartTimeout
+	}
+	// The timeout bounds connecting only: an event stream may stay open
+	// for as long as the client wants it.
+	deadline := time.Now().Add(timeout)
+
+	target := fmt.Sprintf("http://localhost:%d%s", p.AppPort, r.URL.RequestURI())
+	var resp *http.Response
+	for {
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, target, bytes.NewReader(body))
+		if err != nil {
+			http.Error(w, "refproxy: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		req.Header = r.Header.Clone()
+		delHopByHop(req.Header)
+		req.Header.Set("X-Forwarded-For", r.RemoteAddr)
+		req.Host = r.Host
+		resp, err = p.client.Do(req)
+		if err == nil {
+			break
+		}
+		if r.Context().Err() != nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			http.Error(w, "refproxy: app not reachable within "+timeout.String(), http.StatusBadGateway)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	defer resp.Body.Close()
+
+	for k, vv := range resp.Header {
+		w.Header()[k] = append([]string(nil), vv...)
+	}
+	delHopByHop(w.Header())
+
+	if IsHTML(resp.Header) && r.Method != http.MethodHead {
+		p.inject(w, resp)
+		return
+	}
+	w.WriteHeader(resp.StatusCode)
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// inject decodes an HTML page, adds Script and sends it uncompressed. A page
+// in an encoding it cannot decode is passed through untouched.
+func (p *Proxy) inject(w http.ResponseWriter, resp *http.Response) {
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, "refproxy: read response: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	page, err := Decode(raw, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		w.Header().Set("Content-Length", strconv.Itoa(len(raw)))
+		w.WriteHeader(resp.StatusCode)
+		w.Write(raw)
+		return
+	}
+	page = Inject(page)
+	w.Header().Del("Content-Encoding")
+	w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+	w.WriteHeader(resp.StatusCode)
+	w.Write(page)
+}
+
+// Inject adds Script before the last </body> of page, or at its end when
+// there is none.
+func Inject(page []byte) []byte {
+	i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if i < 0 {
+		i = len(page)
+	}
+	out := make([]byte, 0, len(page)+len(Script))
+	out = append(out, page[:i]...)
+	out = append(out, Script...)
+	return append(out, page[i:]...)
+}
+
+// IsHTML reports whether the response is an HTML page.
+func IsHTML(h http.Header) bool {
+	return strings.Contains(strings.ToLower(h.Get("Content-Type")), "text/html")
+}
+
+// delHopByHop removes the hop-by-hop headers from h, including the ones
+// named by Connection.
+func delHopByHop(h http.Header) {
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = textproto.TrimString(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopByHop {
+		h.Del(name)
+	}
+}
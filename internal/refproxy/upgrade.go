@@ -0,0 +1,98 @@
+package refproxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// upgrade reports whether r asks to switch protocols, e.g. to WebSocket:
+// Connection lists "upgrade" and Upgrade names the protocol.
+func upgrade(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tunnel sends an upgrade request to the app with its Connection and
+// Upgrade headers, then copies bytes both ways, whatever the app answers,
+// until one side closes. Like forward, it retries while the app does not
+// accept connections.
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "refproxy: upgrade unsupported", http.StatusInternalServerError)
+		return
+	}
+	timeout := p.StartTimeout
+	if timeout == 0 {
+		timeout = DefaultStartTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	addr := fmt.Sprintf("localhost:%d", p.AppPort)
+	var app net.Conn
+	for {
+		var err error
+		app, err = net.Dial("tcp", addr)
+		if err == nil {
+			break
+		}
+		if r.Context().Err() != nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			http.Error(w, "refproxy: app not reachable within "+timeout.String(), http.StatusBadGateway)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	defer app.Close()
+
+	out := r.Clone(r.Context())
+	// Drop the hop-by-hop headers but the two that ask for the upgrade.
+	connection, proto := r.Header.Get("Connection"), r.Header.Get("Upgrade")
+	delHopByHop(out.Header)
+	out.Header.Set("Connection", connection)
+	out.Header.Set("Upgrade", proto)
+	out.Header.Set("X-Forwarded-For", r.RemoteAddr)
+	if err := out.Write(app); err != nil {
+		http.Error(w, "refproxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	client, buf, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	// Bytes the client sent after the request head go first.
+	if n := buf.Reader.Buffered(); n > 0 {
+		head, _ := buf.Reader.Peek(n)
+		if _, err := app.Write(head); err != nil {
+			return
+		}
+	}
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(app, client)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(client, app)
+		done <- struct{}{}
+	}()
+	// Either side closing ends the tunnel; the deferred closes end the
+	// other copy.
+	<-done
+}
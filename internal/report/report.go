@@ -0,0 +1,191 @@
+// Package report writes the results of a collection run as JSON and as
+// JUnit XML for CI dashboards.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+)
+
+const (
+	// JSONFile and JUnitFile are the names used by WriteDir.
+	JSONFile  = "repro.json"
+	JUnitFile = "junit.xml"
+)
+
+// Report is one run of the collection, possibly across several air versions.
+type Report struct {
+	Started  time.Time `json:"started"`
+	Duration float64   `json:"duration_seconds"`
+	Results  []Entry   `json:"results"`
+}
+
+// Entry is the outcome of one example against one air binary.
+type Entry struct {
+	Example    string  `json:"example"`
+	AirVersion string  `json:"air_version"`
+	Verdict    string  `json:"verdict"`
+	Reason     string  `json:"reason,omitempty"`
+	Started    string  `json:"started"`
+	Duration   float64 `json:"duration_seconds"`
+	AirLog     string  `json:"air_log"`
+	AppLog     string  `json:"app_log"`
+}
+
+// New starts an empty report.
+func New() *Report {
+	return &Report{Started: time.Now()}
+}
+
+// Add records a result.
+func (r *Report) Add(res runner.Result) {
+	air, app := res.Logs()
+	r.Results = append(r.Results, Entry{
+		Example:    res.Example,
+		AirVersion: res.AirVersion,
+		Verdict:    string(res.Verdict),
+		Reason:     res.Reason,
+		Started:    res.Started.Format(time.RFC3339Nano),
+		Duration:   res.Duration.Seconds(),
+		AirLog:     air,
+		AppLog:     app,
+	})
+}
+
+// Finish stamps the total duration.
+func (r *Report) Finish() {
+	r.Duration = time.Since(r.Started).Seconds()
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Tests   int          `xml:"tests,attr"`
+	Fail    int          `xml:"failures,attr"`
+	Errors  int          `xml:"errors,attr"`
+	Skipped int          `xml:"skipped,attr"`
+	Time    float64      `xml:"time,attr"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Fail      int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      float64     `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+}
+
+// WriteJUnit writes one testsuite per air version. A reproduced bug is a
+// failure, a harness problem an error; the app's output goes to system-out
+// and air's own messages to system-err.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	doc := junitSuites{Time: r.Duration}
+	index := map[string]int{}
+	for _, e := range r.Results {
+		i, ok := index[e.AirVersion]
+		if !ok {
+			i = len(doc.Suites)
+			index[e.AirVersion] = i
+			doc.Suites = append(doc.Suites, junitSuite{
+				Name:      "air " + e.AirVersion,
+				Timestamp: e.Started,
+			})
+		}
+		s := &doc.Suites[i]
+		c := junitCase{
+			Name:      e.Example,
+			Classname: "air-repro." + e.AirVersion,
+			Time:      e.Duration,
+			SystemOut: e.AppLog,
+			SystemErr: e.AirLog,
+		}
+		msg := &junitMessage{Message: e.Reason, Type: e.Verdict}
+		switch runner.Verdict(e.Verdict) {
+		case runner.Bug:
+			c.Failure = msg
+			s.Fail++
+		case runner.Error:
+			c.Error = msg
+			s.Errors++
+		case runner.Skip:
+			c.Skipped = msg
+			s.Skipped++
+		}
+		s.Tests++
+		s.Time += e.Duration
+		s.Cases = append(s.Cases, c)
+	}
+	for _, s := range doc.Suites {
+		doc.Tests += s.Tests
+		doc.Fail += s.Fail
+		doc.Errors += s.Errors
+		doc.Skipped += s.Skipped
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteDir writes repro.json and junit.xml into dir.
+func (r *Report) WriteDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for name, write := range map[string]func(io.Writer) error{
+		JSONFile:  r.WriteJSON,
+		JUnitFile: r.WriteJUnit,
+	} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := write(f); err != nil {
+			f.Close()
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/editsave"
+)
+
+// original is the state of a file before the session first touched it.
+type original struct {
+	data   []byte
+	mode   fs.FileMode
+	exists bool
+	// dirs are the missing parent directories of a new file, innermost
+	// first, removed again on restore.
+	dirs []string
+}
+
+// editor applies edits inside an example directory and remembers how to
+// undo them.
+type editor struct {
+	dir   string
+	saved map[string]original
+	order []string
+	// at is when each edit started, in order.
+	at []time.Time
+}
+
+func newEditor(dir string) *editor {
+	return &editor{dir: dir, saved: map[string]original{}}
+}
+
+func (e *editor) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(e.dir, filepath.FromSlash(name))
+}
+
+// save records the original content of name the first time it is edited.
+func (e *editor) save(name string) (string, error) {
+	e.at = append(e.at, time.Now())
+	p := e.path(name)
+	if _, ok := e.saved[p]; ok {
+		return p, nil
+	}
+	info, err := os.Stat(p)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		var dirs []string
+		for d := filepath.Dir(p); d != e.dir && d != filepath.Dir(d); d = filepath.Dir(d) {
+			if _, err := os.Stat(d); err == nil {
+				break
+			}
+			dirs = append(dirs, d)
+		}
+		e.saved[p] = original{dirs: dirs}
+	case err != nil:
+		return "", err
+	default:
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		e.saved[p] = original{data: data, mode: info.Mode().Perm(), exists: true}
+	}
+	e.order = append(e.order, p)
+	return p, nil
+}
+
+// restore puts every edited file back, newest edit first, and removes files
+// that did not exist before.
+func (e *editor) restore() error {
+	var errs []error
+	for i := len(e.order) - 1; i >= 0; i-- {
+		p := e.order[i]
+		orig := e.saved[p]
+		if !orig.exists {
+			if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			for _, d := range orig.dirs {
+				// Leave directories that something else wrote to.
+				if os.Remove(d) != nil {
+					break
+				}
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.WriteFile(p, orig.data, orig.mode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	e.saved = map[string]original{}
+	e.order = nil
+	return errors.Join(errs...)
+}
+
+// write stores data at p the way how saves files; an empty how is a plain
+// os.WriteFile.
+func write(p string, data []byte, how editsave.Strategy) error {
+	if how != "" {
+		return editsave.Save(p, data, how)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o644)
+}
+
+// WriteFile replaces the content of name, relative to the example directory.
+func (s *Session) WriteFile(name, content string, how editsave.Strategy) error {
+	p, err := s.edits.save(name)
+	if err != nil {
+		return err
+	}
+	return write(p, []byte(content), how)
+}
+
+// AppendFile appends text to name, like `echo text >> name`, or saves the
+// longer file with how.
+func (s *Session) AppendFile(name, text string, how editsave.Strategy) error {
+	p, err := s.edits.save(name)
+	if err != nil {
+		return err
+	}
+	if how != "" {
+		data, err := os.ReadFile(p)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return editsave.Save(p, append(data, text...), how)
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReplaceInFile rewrites every match of pattern in name, like `sed -i`.
+func (s *Session) ReplaceInFile(name, pattern, repl string, how editsave.Strategy) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	p, err := s.edits.save(name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	if !re.Match(data) {
+		return fmt.Errorf("%s: no match for %q", name, pattern)
+	}
+	return write(p, re.ReplaceAll(data, []byte(repl)), how)
+}
+
+// Touch rewrites name with its current content so watchers see a write
+// event, like saving a file without changing it. A missing file is created
+// empty.
+func (s *Session) Touch(name string, how editsave.Strategy) error {
+	p, err := s.edits.save(name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return write(p, data, how)
+}
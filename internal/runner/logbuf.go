@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ansi matches the color escape sequences air emits when it thinks it is
+// talking to a terminal.
+var ansi = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// logBuffer collects air's combined output and lets waiters block until
+// something new is written.
+type logBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	notify chan struct{}
+	// stamps holds the end offset and arrival time of every write.
+	stamps []stamp
+}
+
+type stamp struct {
+	end int
+	at  time.Time
+}
+
+func newLogBuffer() *logBuffer {
+	return &logBuffer{notify: make(chan struct{})}
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n, err := b.buf.Write(p)
+	b.stamps = append(b.stamps, stamp{end: b.buf.Len(), at: time.Now()})
+	close(b.notify)
+	b.notify = make(chan struct{})
+	return n, err
+}
+
+// changed returns a channel that is closed on the next write.
+func (b *logBuffer) changed() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.notify
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return ansi.ReplaceAllString(b.buf.String(), "")
+}
+
+// times returns when each occurrence of substr was written, as the arrival
+// time of the write that completed it.
+func (b *logBuffer) times(substr string) []time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []time.Time
+	data, sub := b.buf.Bytes(), []byte(substr)
+	for off := 0; ; {
+		i := bytes.Index(data[off:], sub)
+		if i < 0 || len(sub) == 0 {
+			return out
+		}
+		off += i + len(sub)
+		j := sort.Search(len(b.stamps), func(j int) bool { return b.stamps[j].end >= off })
+		out = append(out, b.stamps[j].at)
+	}
+}
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"regexp"
+	"strings"
+)
+
+// airLine matches the lines air writes itself, optionally prefixed with the
+// "[15:04:05] " stamp of log.time = true. Everything else comes from the
+// build command or the app.
+var airLine = regexp.MustCompile(`^(\[\d\d:\d\d:\d\d\] )?(` + strings.Join([]string{
+	`watching `,
+	`!exclude `,
+	`building\.\.\.`,
+	`running\.\.\.`,
+	`cleaning\.\.\.`,
+	`see you again~`,
+	`\S+ has changed`,
+	`skipping \S+ because contents unchanged`,
+	`failed to `,
+	`Process Exit with Code`,
+	`> `,
+	`mkdir `,
+	`deleting `,
+	`include_dir `,
+	`Proxy server listening`,
+	`proxy handler: `,
+	`\s*__    _   ___`,
+	`\s*/ /\\  \| \| \| \|_\)`,
+	`\s*/_/--\\ \|_\| \|_\| \\_`,
+	`\S+, built with Go`,
+	`\[warning\] `,
+	`\d{4}/\d\d/\d\d \d\d:\d\d:\d\d failed to parse \S+`,
+}, "|") + `)`)
+
+// SplitLog separates air's own messages from the output of the build
+// command and the app. It goes by the messages air is known to print, so
+// it is a best effort across air versions.
+func SplitLog(log string) (air, app string) {
+	var a, b strings.Builder
+	for _, line := range strings.SplitAfter(log, "\n") {
+		if line == "" {
+			continue
+		}
+		if airLine.MatchString(line) {
+			a.WriteString(line)
+		} else {
+			b.WriteString(line)
+		}
+	}
+	return a.String(), b.String()
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package runner
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate kills the whole tree, since Windows has no process groups that
+// can be signalled the way Unix ones can (see window-kill-twice).
+func terminate(cmd *exec.Cmd) {
+	_ = exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
+
+func kill(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
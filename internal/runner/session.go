@@ -0,0 +1,278 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Options describes how to start air for one example.
+type Options struct {
+	// AirBin is the air executable. A bare name is looked up in PATH and an
+	// empty value falls back to FindAir.
+	AirBin string
+	// Dir is the example directory air is started in.
+	Dir string
+	// Args are extra command line arguments passed to air.
+	Args []string
+	// Env is appended to the current environment.
+	Env []string
+}
+
+func (o Options) name() string {
+	return filepath.Base(filepath.Clean(o.Dir))
+}
+
+// Session is a running air process plus the edits applied to its example.
+type Session struct {
+	dir     string
+	started time.Time
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	out     *logBuffer
+	edits   *editor
+	done    chan struct{}
+	waitErr error
+}
+
+// FindAir returns the air binary named by $AIR_BIN, or the one in PATH.
+func FindAir() (string, error) {
+	if bin := os.Getenv("AIR_BIN"); bin != "" {
+		return resolveBin(bin)
+	}
+	return resolveBin("air")
+}
+
+func resolveBin(bin string) (string, error) {
+	if !strings.ContainsRune(bin, filepath.Separator) && !strings.ContainsRune(bin, '/') {
+		return exec.LookPath(bin)
+	}
+	abs, err := filepath.Abs(bin)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return "", fmt.Errorf("air binary: %w", err)
+	}
+	return abs, nil
+}
+
+// LocateAir resolves bin the way Options.AirBin is resolved.
+func LocateAir(bin string) (string, error) {
+	if bin == "" {
+		return FindAir()
+	}
+	return resolveBin(bin)
+}
+
+// Start launches air in opts.Dir. The caller must call Stop.
+func Start(opts Options) (*Session, error) {
+	bin, err := LocateAir(opts.AirBin)
+	if err != nil {
+		return nil, fmt.Errorf("locate air: %w", err)
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("example directory: %w", err)
+	}
+
+	s := &Session{
+		dir:   dir,
+		out:   newLogBuffer(),
+		edits: newEditor(dir),
+		done:  make(chan struct{}),
+	}
+	s.cmd = exec.Command(bin, opts.Args...)
+	s.cmd.Dir = dir
+	s.cmd.Env = append(os.Environ(), opts.Env...)
+	s.cmd.Stdout = s.out
+	s.cmd.Stderr = s.out
+	if s.stdin, err = s.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	setProcessGroup(s.cmd)
+	s.started = time.Now()
+	if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start air: %w", err)
+	}
+	go func() {
+		s.waitErr = s.cmd.Wait()
+		close(s.done)
+	}()
+	return s, nil
+}
+
+// Dir is the absolute path of the example directory.
+func (s *Session) Dir() string { return s.dir }
+
+// Pid is the process id of air.
+func (s *Session) Pid() int { return s.cmd.Process.Pid }
+
+// Started is when air was started.
+func (s *Session) Started() time.Time { return s.started }
+
+// Log returns everything air has written so far.
+func (s *Session) Log() string { return s.out.String() }
+
+// Times returns when each occurrence of substr in the log was written.
+// Unlike Count it matches the raw output, color codes included.
+func (s *Session) Times(substr string) []time.Time {
+	return s.out.times(substr)
+}
+
+// Edits returns when each edit made through the session started.
+func (s *Session) Edits() []time.Time {
+	return slices.Clone(s.edits.at)
+}
+
+// Count returns how many times substr appears in the log.
+func (s *Session) Count(substr string) int {
+	return strings.Count(s.Log(), substr)
+}
+
+// SendKeys writes keys to air's stdin, as if typed in the terminal.
+func (s *Session) SendKeys(keys string) error {
+	_, err := io.WriteString(s.stdin, keys)
+	return err
+}
+
+// Exited reports whether the air process has terminated.
+func (s *Session) Exited() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop terminates air together with the app it started and restores every
+// file touched through the session.
+func (s *Session) Stop() error {
+	if !s.Exited() {
+		terminate(s.cmd)
+		select {
+		case <-s.done:
+		case <-time.After(5 * time.Second):
+			kill(s.cmd)
+			<-s.done
+		}
+	}
+	return s.edits.restore()
+}
+
+// errExited is returned by the wait helpers when air is gone.
+var errExited = errors.New("air exited")
+
+// WaitFor polls cond against the log until it holds or timeout passes.
+func (s *Session) WaitFor(timeout time.Duration, cond func(log string) bool) error {
+	deadline := time.After(timeout)
+	for {
+		changed := s.out.changed()
+		if cond(s.Log()) {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-s.done:
+			if cond(s.Log()) {
+				return nil
+			}
+			return fmt.Errorf("%w: %v", errExited, s.waitErr)
+		case <-deadline:
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+	}
+}
+
+// WaitLog waits until substr appears in the log at least n times.
+func (s *Session) WaitLog(substr string, n int, timeout time.Duration) error {
+	err := s.WaitFor(timeout, func(log string) bool {
+		return strings.Count(log, substr) >= n
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %q (x%d): %w", substr, n, err)
+	}
+	return nil
+}
+
+// Settle waits until air has been quiet for the given duration, giving up
+// after timeout.
+func (s *Session) Settle(quiet, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		changed := s.out.changed()
+		select {
+		case <-changed:
+		case <-s.done:
+			return nil
+		case <-time.After(quiet):
+			return nil
+		case <-deadline:
+			return fmt.Errorf("log still busy after %s", timeout)
+		}
+	}
+}
+
+// Sleep waits for d unless air exits first.
+func (s *Session) Sleep(d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-s.done:
+		return fmt.Errorf("%w: %v", errExited, s.waitErr)
+	}
+}
+
+var httpClient = &http.Client{Timeout: 5 * time.Second}
+
+// Get fetches url and returns its body.
+func (s *Session) Get(url string) (string, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 400 {
+		return string(body), fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return string(body), nil
+}
+
+// WaitHTTP polls url until its body contains substr and returns the last
+// body it saw, which is useful for explaining a timeout.
+func (s *Session) WaitHTTP(url, substr string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	var body string
+	var err error
+	for {
+		body, err = s.Get(url)
+		if err == nil && strings.Contains(body, substr) {
+			return body, nil
+		}
+		if s.Exited() {
+			return body, fmt.Errorf("%w: %v", errExited, s.waitErr)
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return body, fmt.Errorf("GET %s: %w", url, err)
+			}
+			return body, fmt.Errorf("GET %s: %q not found after %s", url, substr, timeout)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
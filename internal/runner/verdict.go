@@ -0,0 +1,94 @@
+// Package runner drives an Air binary inside one of the example directories,
+// applies scripted file edits, waits on log and HTTP conditions, and turns the
+// outcome into a PASS/BUG/ERROR verdict.
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Verdict is the outcome of running one reproduction.
+type Verdict string
+
+const (
+	// Pass means Air behaved the way the example says it should.
+	Pass Verdict = "PASS"
+	// Bug means the upstream bug still reproduces.
+	Bug Verdict = "BUG"
+	// Error means the harness could not reach a conclusion (air missing,
+	// port in use, app never came up, ...).
+	Error Verdict = "ERROR"
+	// Skip means the reproduction does not apply here, e.g. a Windows-only
+	// bug on Linux.
+	Skip Verdict = "SKIP"
+)
+
+// BugError is returned by a Check when the bug reproduced.
+type BugError struct {
+	msg string
+}
+
+func (e *BugError) Error() string { return e.msg }
+
+// Bugf reports that the bug reproduced.
+func Bugf(format string, args ...any) error {
+	return &BugError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Check drives a running session and decides the verdict: nil means PASS,
+// a *BugError means BUG and anything else means ERROR.
+type Check func(s *Session) error
+
+// Result is the structured outcome of a Run.
+type Result struct {
+	Example  string
+	Verdict  Verdict
+	Reason   string
+	Started  time.Time
+	Duration time.Duration
+	// AirVersion is what the air binary reports for -v.
+	AirVersion string
+	// AirLog is everything air (and the app it started) wrote to
+	// stdout and stderr, with color codes stripped.
+	AirLog string
+}
+
+// Run starts air with opts, runs check against it and always stops air and
+// restores every edited file before returning.
+func Run(opts Options, check Check) Result {
+	res := Result{Example: opts.name(), Started: time.Now(), AirVersion: AirVersion(opts.AirBin)}
+	s, err := Start(opts)
+	if err != nil {
+		res.Verdict, res.Reason = Error, err.Error()
+		res.Duration = time.Since(res.Started)
+		return res
+	}
+	checkErr := check(s)
+	stopErr := s.Stop()
+	res.AirLog = s.Log()
+	res.Duration = time.Since(res.Started)
+	res.Verdict, res.Reason = classify(checkErr)
+	if res.Verdict != Error && stopErr != nil {
+		res.Verdict, res.Reason = Error, stopErr.Error()
+	}
+	return res
+}
+
+// Logs splits AirLog into air's own messages and the build/app output.
+func (r Result) Logs() (air, app string) {
+	return SplitLog(r.AirLog)
+}
+
+func classify(err error) (Verdict, string) {
+	var bug *BugError
+	switch {
+	case err == nil:
+		return Pass, ""
+	case errors.As(err, &bug):
+		return Bug, bug.msg
+	default:
+		return Error, err.Error()
+	}
+}
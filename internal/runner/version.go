@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"os/exec"
+	"regexp"
+	"sync"
+)
+
+// versionLine matches the banner line air prints for -v, e.g.
+// "v1.61.7, built with Go go1.23.4".
+var versionLine = regexp.MustCompile(`(\S+), built with Go`)
+
+var versions sync.Map // resolved binary path -> version
+
+// AirVersion returns the version air reports for -v, or "unknown". bin is
+// resolved like Options.AirBin.
+func AirVersion(bin string) string {
+	path, err := LocateAir(bin)
+	if err != nil {
+		return "unknown"
+	}
+	if v, ok := versions.Load(path); ok {
+		return v.(string)
+	}
+	v := "unknown"
+	out, _ := exec.Command(path, "-v").CombinedOutput()
+	if m := versionLine.FindSubmatch(ansi.ReplaceAll(out, nil)); m != nil {
+		v = string(m[1])
+	}
+	versions.Store(path, v)
+	return v
+}
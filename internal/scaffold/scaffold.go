@@ -0,0 +1,169 @@
+// Package scaffold creates a new example directory from one of the
+// templates under templates/, so every reproduction starts with the same
+// layout: main.go, go.mod, .air.toml, README.md, scenario.toml and meta.toml.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/air-verse/air-reproducible-example/internal/catalog"
+)
+
+//go:embed all:templates
+var templates embed.FS
+
+// Templates are the kinds of app a new example can start from.
+var Templates = []string{"http", "gin", "proxy"}
+
+// firstPort is where the search for an unused port starts.
+const firstPort = 8082
+
+// Options describes the example to create.
+type Options struct {
+	// Root is the repository root the example directory is created in.
+	Root string
+	// Issue is the air-verse/air issue number.
+	Issue int
+	// Slug is appended to the directory name: issue-<Issue>-<Slug>.
+	Slug string
+	// Template is one of Templates.
+	Template string
+}
+
+// data is what the templates see.
+type data struct {
+	Name      string
+	Issue     int
+	Template  string
+	Port      int
+	ProxyPort int
+}
+
+var slugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// DirName is the directory an example for issue and slug is created in.
+func DirName(issue int, slug string) string {
+	name := "issue-" + strconv.Itoa(issue)
+	if slug = strings.Trim(slugRe.ReplaceAllString(strings.ToLower(slug), "-"), "-"); slug != "" {
+		name += "-" + slug
+	}
+	return name
+}
+
+// Create writes the example and returns its directory.
+func Create(opts Options) (string, error) {
+	if opts.Issue <= 0 {
+		return "", errors.New("issue number must be positive")
+	}
+	if !slices.Contains(Templates, opts.Template) {
+		return "", fmt.Errorf("unknown template %q (want one of %s)", opts.Template, strings.Join(Templates, ", "))
+	}
+	d := data{
+		Name:     DirName(opts.Issue, opts.Slug),
+		Issue:    opts.Issue,
+		Template: opts.Template,
+	}
+	dir := filepath.Join(opts.Root, d.Name)
+	if _, err := os.Stat(dir); err == nil {
+		return "", fmt.Errorf("%s already exists", dir)
+	}
+
+	n := 1
+	if opts.Template == "proxy" {
+		n = 2
+	}
+	ports, err := freePorts(opts.Root, n)
+	if err != nil {
+		return "", err
+	}
+	d.Port = ports[0]
+	if n == 2 {
+		d.ProxyPort = ports[1]
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", err
+	}
+	for _, sub := range []string{"common", opts.Template} {
+		if err := render(dir, path.Join("templates", sub), d); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// render executes every *.tmpl in src and writes it to dir without the
+// suffix.
+func render(dir, src string, d data) error {
+	entries, err := fs.ReadDir(templates, src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".tmpl")
+		if !ok {
+			continue
+		}
+		t, err := template.ParseFS(templates, path.Join(src, e.Name()))
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, d); err != nil {
+			return fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// freePorts returns n ports that no other example declares in its
+// meta.toml and that nothing on this machine is listening on.
+func freePorts(root string, n int) ([]int, error) {
+	dirs, err := catalog.Dirs(root)
+	if err != nil {
+		return nil, err
+	}
+	taken := map[int]bool{}
+	for _, dir := range dirs {
+		e, err := catalog.Load(dir)
+		if err != nil {
+			continue
+		}
+		for _, p := range e.Ports {
+			taken[p] = true
+		}
+	}
+	var ports []int
+	for p := firstPort; p < 65536 && len(ports) < n; p++ {
+		if taken[p] {
+			continue
+		}
+		l, err := net.Listen("tcp", ":"+strconv.Itoa(p))
+		if err != nil {
+			continue
+		}
+		l.Close()
+		ports = append(ports, p)
+	}
+	if len(ports) < n {
+		return nil, errors.New("no free port found")
+	}
+	return ports, nil
+}
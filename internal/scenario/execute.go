@@ -0,0 +1,364 @@
+package scenario
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/editsave"
+	"github.com/air-verse/air-reproducible-example/internal/ports"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/reprokit"
+)
+
+const (
+	defaultReadyTimeout = 2 * time.Minute
+	defaultWithin       = 10 * time.Second
+)
+
+// Execute removes the scenario's Clean paths, leases free ports for the
+// roles in meta.toml, starts air in its example directory and runs the
+// steps against it.
+func Execute(sc *Scenario, airBin string) runner.Result {
+	res, _ := Measure(sc, airBin)
+	return res
+}
+
+// Measure is Execute that also reports the Metrics of the run. They are
+// zero when air never started.
+func Measure(sc *Scenario, airBin string) (runner.Result, Metrics) {
+	var m Metrics
+	res := ExecuteFunc(sc, airBin, func(run *Scenario, s *runner.Session) error {
+		err := run.Check(s)
+		m = run.measure(s)
+		return err
+	})
+	return res, m
+}
+
+// ExecuteFunc prepares and starts air like Execute but drives it with check
+// instead of the scenario's steps. run is the scenario with its
+// placeholders expanded for the leased ports, which are in run.Ports.
+func ExecuteFunc(sc *Scenario, airBin string, check func(run *Scenario, s *runner.Session) error) runner.Result {
+	early := runner.Result{Example: sc.Name(), Started: time.Now(), AirVersion: runner.AirVersion(airBin)}
+	if !sc.Applies() {
+		early.Verdict, early.Reason = runner.Skip, "only reproduces on "+strings.Join(sc.Platforms, ", ")
+		return early
+	}
+	for _, p := range sc.Clean {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(sc.Dir, p)
+		}
+		if err := os.RemoveAll(p); err != nil {
+			early.Verdict, early.Reason = runner.Error, err.Error()
+			return early
+		}
+	}
+	roles := make([]string, 0, len(sc.Ports))
+	for role := range sc.Ports {
+		roles = append(roles, role)
+	}
+	leased, err := ports.Lease(roles)
+	if err != nil {
+		early.Verdict, early.Reason = runner.Error, err.Error()
+		return early
+	}
+	defer ports.Release(leased)
+	run := sc.expand(ports.Vars(leased))
+	run.Ports = leased
+	if p, ok := leased["app"]; ok {
+		run.infoURL = fmt.Sprintf("http://localhost:%d%s", p, reprokit.InfoPath)
+	}
+
+	args, cleanup, err := run.configArgs(leased)
+	if err != nil {
+		early.Verdict, early.Reason = runner.Error, err.Error()
+		return early
+	}
+	defer cleanup()
+	opts := runner.Options{
+		AirBin: airBin,
+		Dir:    sc.Dir,
+		Args:   args,
+		Env:    append(ports.Environ(leased), run.Env...),
+	}
+	res := runner.Run(opts, func(s *runner.Session) error {
+		return check(run, s)
+	})
+	res.Example = sc.Name()
+	return res
+}
+
+// configArgs returns the air arguments for a run on the leased ports. When
+// the example's config sets proxy ports, or the scenario forces a watcher,
+// a copy with those changes is written to a temporary directory and passed
+// with -c.
+func (sc *Scenario) configArgs(leased map[string]int) ([]string, func(), error) {
+	args := slices.Clone(sc.Args)
+	cfgArg := slices.Index(args, "-c") + 1
+	name := ".air.toml"
+	if cfgArg > 0 && cfgArg < len(args) {
+		name = args[cfgArg]
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(sc.Dir, name)
+	}
+	src, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) && sc.watcher == nil {
+		return args, func() {}, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	cfg := ports.RewriteConfig(src, leased)
+	if sc.watcher != nil {
+		cfg = sc.watcher.apply(cfg)
+	}
+	if bytes.Equal(cfg, src) {
+		return args, func() {}, nil
+	}
+	tmp, err := os.MkdirTemp("", "air-repro-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	generated := filepath.Join(tmp, filepath.Base(name))
+	if err := os.WriteFile(generated, cfg, 0o644); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if cfgArg > 0 && cfgArg < len(args) {
+		args[cfgArg] = generated
+	} else {
+		args = append([]string{"-c", generated}, args...)
+	}
+	return args, cleanup, nil
+}
+
+// placeholder matches ${NAME} in scenario strings.
+var placeholder = regexp.MustCompile(`\$\{([A-Z][A-Z0-9_]*)\}`)
+
+// expand returns a copy of the scenario with the placeholders in vars
+// replaced; unknown ones are left alone.
+func (sc *Scenario) expand(vars map[string]string) *Scenario {
+	x := func(s string) string {
+		return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+			if v, ok := vars[m[2:len(m)-1]]; ok {
+				return v
+			}
+			return m
+		})
+	}
+	out := *sc
+	out.Ready = x(sc.Ready)
+	out.StartupBug = x(sc.StartupBug)
+	out.Args = make([]string, len(sc.Args))
+	for i, a := range sc.Args {
+		out.Args[i] = x(a)
+	}
+	out.Env = make([]string, len(sc.Env))
+	for i, e := range sc.Env {
+		out.Env[i] = x(e)
+	}
+	out.Steps = make([]Step, len(sc.Steps))
+	for i, st := range sc.Steps {
+		for _, f := range []*string{
+			&st.Text, &st.With, &st.Contains, &st.NotContains, &st.Get,
+			&st.ExpectLog, &st.ExpectNoLog, &st.WaitLog, &st.Keys, &st.Bug,
+		} {
+			*f = x(*f)
+		}
+		out.Steps[i] = st
+	}
+	return &out
+}
+
+// Check is the runner.Check that performs the scenario.
+func (sc *Scenario) Check(s *runner.Session) error {
+	if sc.Ready != "" {
+		timeout := sc.ReadyTimeout.Duration
+		if timeout == 0 {
+			timeout = defaultReadyTimeout
+		}
+		if err := s.WaitLog(sc.Ready, 1, timeout); err != nil {
+			if sc.StartupBug != "" {
+				return runner.Bugf("%s: %v", sc.StartupBug, err)
+			}
+			return fmt.Errorf("startup: %w", err)
+		}
+	}
+	// ready is the READY count before the edit that expect_rebuild waits on,
+	// so a restart that finishes during the steps in between still counts;
+	// -1 when there is no such edit and the step starts the count itself.
+	ready := -1
+	for i, st := range sc.Steps {
+		if sc.skips(st) {
+			continue
+		}
+		if st.action() {
+			ready = s.Count(sc.Ready)
+		}
+		err := sc.run(s, st, ready)
+		if st.Kind() == "expect_rebuild" {
+			ready = -1
+		}
+		if err == nil {
+			continue
+		}
+		kind := st.Kind()
+		if !st.expectation() {
+			return fmt.Errorf("step %d (%s): %w", i+1, kind, err)
+		}
+		if st.Bug != "" {
+			return runner.Bugf("step %d (%s): %s: %v", i+1, kind, st.Bug, err)
+		}
+		return runner.Bugf("step %d (%s): %v", i+1, kind, err)
+	}
+	if sc.freshCheck() {
+		err := sc.expectFresh(s, defaultFreshWithin)
+		switch {
+		case errors.Is(err, errStale):
+			return runner.Bugf("after the last step: %v", err)
+		case err != nil && !errors.Is(err, errNoProvenance):
+			return fmt.Errorf("after the last step: %w", err)
+		}
+	}
+	return nil
+}
+
+// freshCheck reports whether Check ends by making sure the app runs the
+// sources on disk: for reprokit examples unless fresh_check = false.
+func (sc *Scenario) freshCheck() bool {
+	return sc.reprokit && (sc.FreshCheck == nil || *sc.FreshCheck)
+}
+
+// action reports whether the step makes air rebuild: an edit, or keys
+// such as a manual restart.
+func (st Step) action() bool {
+	switch st.Kind() {
+	case "touch", "append", "write", "replace", "keys":
+		return true
+	}
+	return false
+}
+
+func (st Step) expectation() bool {
+	return strings.HasPrefix(st.Kind(), "expect_") || st.Kind() == "get"
+}
+
+func (st Step) within(def time.Duration) time.Duration {
+	if st.Within.Duration > 0 {
+		return st.Within.Duration
+	}
+	return def
+}
+
+// saveWith is the editor strategy of an edit step: its own, else the
+// variant's, else "" for plain writes.
+func (sc *Scenario) saveWith(st Step) editsave.Strategy {
+	if st.Editor != "" {
+		return editsave.Strategy(st.Editor)
+	}
+	return sc.editor
+}
+
+func (st Step) count() int {
+	if st.Count > 0 {
+		return st.Count
+	}
+	return 1
+}
+
+// run performs one step. ready is the READY count expect_rebuild waits
+// to grow past, or -1 to take it when the step starts.
+func (sc *Scenario) run(s *runner.Session, st Step, ready int) error {
+	switch st.Kind() {
+	case "touch":
+		return s.Touch(st.Touch, sc.saveWith(st))
+	case "append":
+		text := st.Text
+		if text == "" {
+			text = "\n"
+		}
+		return s.AppendFile(st.Append, text, sc.saveWith(st))
+	case "write":
+		return s.WriteFile(st.Write, st.Text, sc.saveWith(st))
+	case "replace":
+		return s.ReplaceInFile(st.Replace, st.Pattern, st.With, sc.saveWith(st))
+	case "sleep":
+		return s.Sleep(st.Sleep.Duration)
+	case "settle":
+		return s.Settle(st.Settle.Duration, st.within(defaultReadyTimeout))
+	case "keys":
+		return s.SendKeys(st.Keys)
+	case "wait_log":
+		return s.WaitLog(st.WaitLog, st.count(), st.within(defaultReadyTimeout))
+	case "expect_rebuild":
+		if ready < 0 {
+			ready = s.Count(sc.Ready)
+		}
+		if err := s.WaitLog(sc.Ready, ready+1, st.ExpectRebuild.Duration); err != nil {
+			return fmt.Errorf("app did not restart within %s", st.ExpectRebuild.Duration)
+		}
+		return nil
+	case "expect_no_rebuild":
+		builds, starts := s.Count(BuildMarker), s.Count(sc.Ready)
+		if err := s.Sleep(st.ExpectNoRebuild.Duration); err != nil {
+			return err
+		}
+		if s.Count(BuildMarker) > builds || s.Count(sc.Ready) > starts {
+			return fmt.Errorf("air rebuilt within %s", st.ExpectNoRebuild.Duration)
+		}
+		return nil
+	case "expect_no_loop":
+		return expectNoLoop(s, st.ExpectNoLoop.Duration, st.count())
+	case "expect_fresh":
+		return sc.expectFresh(s, st.ExpectFresh.Duration)
+	case "expect_log":
+		return s.WaitLog(st.ExpectLog, st.count(), st.within(defaultWithin))
+	case "expect_no_log":
+		if st.Within.Duration > 0 {
+			if err := s.Sleep(st.Within.Duration); err != nil && !s.Exited() {
+				return err
+			}
+		}
+		if n := s.Count(st.ExpectNoLog); n > 0 {
+			return fmt.Errorf("%q appeared %d time(s)", st.ExpectNoLog, n)
+		}
+		return nil
+	case "get":
+		url := expandURL(st.Get)
+		var body string
+		var err error
+		if st.Once {
+			body, err = s.Get(url)
+			if err == nil && !strings.Contains(body, st.Contains) {
+				err = fmt.Errorf("GET %s: %q not found", url, st.Contains)
+			}
+		} else {
+			body, err = s.WaitHTTP(url, st.Contains, st.within(5*time.Second))
+		}
+		if err != nil {
+			return err
+		}
+		if st.NotContains != "" && strings.Contains(body, st.NotContains) {
+			return fmt.Errorf("GET %s: body contains %q", url, st.NotContains)
+		}
+		return nil
+	}
+	return errors.New("unknown step")
+}
+
+// expandURL turns the ":8080/version" shorthand into a localhost URL.
+func expandURL(u string) string {
+	if strings.HasPrefix(u, ":") {
+		return "http://localhost" + u
+	}
+	return u
+}
@@ -0,0 +1,86 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/reprokit"
+	"github.com/air-verse/air-reproducible-example/reprokit/provenance"
+)
+
+// defaultFreshWithin bounds how long the final freshness check waits for
+// air to finish rebuilding after the last edit.
+const defaultFreshWithin = 30 * time.Second
+
+// errNoProvenance means the app did not report a source hash, e.g. because
+// the scenario leaves it stopped on purpose.
+var errNoProvenance = errors.New("no provenance")
+
+// errStale means the app runs a binary built from older sources.
+var errStale = errors.New("stale binary")
+
+// expectFresh waits until the binary air runs was built from the sources
+// now on disk. It compares the source_hash the app reports with the same
+// hash over its files on disk: every file compiled in plus every file now
+// matching the app's patterns, so a new file counts as a change too.
+func (sc *Scenario) expectFresh(s *runner.Session, within time.Duration) error {
+	if sc.infoURL == "" {
+		return fmt.Errorf("%w: the example has no app port", errNoProvenance)
+	}
+	deadline := time.Now().Add(within)
+	for {
+		running, disk, err := sc.hashes(s)
+		switch {
+		case err == nil && running == disk:
+			return nil
+		case s.Exited() || time.Now().After(deadline):
+			if err != nil {
+				return err
+			}
+			if disk == "" {
+				return fmt.Errorf("%w: a file compiled into the app (sources %.12s) is gone from disk", errStale, running)
+			}
+			return fmt.Errorf("%w: the app was built from sources %.12s but the files on disk hash to %.12s after %s", errStale, running, disk, within)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// hashes returns the source hash reported by the running app and the one of
+// the same files on disk, "" when one of them is missing.
+func (sc *Scenario) hashes(s *runner.Session) (running, disk string, err error) {
+	body, err := s.Get(sc.infoURL)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", errNoProvenance, err)
+	}
+	var info reprokit.Info
+	if err := json.Unmarshal([]byte(body), &info); err != nil {
+		return "", "", fmt.Errorf("%s: %w", reprokit.InfoPath, err)
+	}
+	b := info.Build
+	if b.SourceHash == "" {
+		return "", "", fmt.Errorf("%w: %s has no source_hash", errNoProvenance, reprokit.InfoPath)
+	}
+	dir := filepath.Join(sc.Dir, filepath.FromSlash(b.Package))
+	files := slices.Clone(b.Files)
+	for _, p := range b.Patterns {
+		onDisk, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(p)))
+		for _, f := range onDisk {
+			name, err := filepath.Rel(dir, f)
+			if err != nil {
+				continue
+			}
+			if name = filepath.ToSlash(name); !slices.Contains(files, name) {
+				files = append(files, name)
+			}
+		}
+	}
+	disk, _ = provenance.Hash(os.DirFS(dir), files)
+	return b.SourceHash, disk, nil
+}
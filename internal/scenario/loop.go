@@ -0,0 +1,61 @@
+package scenario
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+)
+
+// changedSuffix ends the line air logs for every file event it acts on,
+// "<path> has changed".
+const changedSuffix = " has changed"
+
+// logTime is the prefix air puts on its lines with [log] time = true.
+var logTime = regexp.MustCompile(`^\[\d{2}:\d{2}:\d{2}\] `)
+
+// expectNoLoop watches air for window and fails when it starts more than max
+// builds, the sign of a rebuild loop such as a pre_cmd generator rewriting a
+// watched file. The error names the file air reported changed most often
+// in the window, the likely culprit.
+func expectNoLoop(s *runner.Session, window time.Duration, max int) error {
+	offset, builds := len(s.Log()), s.Count(BuildMarker)
+	if err := s.Sleep(window); err != nil {
+		return err
+	}
+	n := s.Count(BuildMarker) - builds
+	if n <= max {
+		return nil
+	}
+	file, times := culprit(s.Log()[offset:])
+	if file == "" {
+		return fmt.Errorf("air built %d times in %s without reporting a changed file", n, window)
+	}
+	return fmt.Errorf("air built %d times in %s: a rebuild loop, %s changed %d times", n, window, file, times)
+}
+
+// culprit returns the file air reported changed most often in log, ties
+// going to the one reported first.
+func culprit(log string) (file string, times int) {
+	counts := map[string]int{}
+	var order []string
+	for _, line := range strings.Split(log, "\n") {
+		line = logTime.ReplaceAllString(strings.TrimSpace(line), "")
+		name, ok := strings.CutSuffix(line, changedSuffix)
+		if !ok || name == "" {
+			continue
+		}
+		if counts[name] == 0 {
+			order = append(order, name)
+		}
+		counts[name]++
+	}
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+	if len(order) == 0 {
+		return "", 0
+	}
+	return order[0], counts[order[0]]
+}
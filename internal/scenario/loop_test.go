@@ -0,0 +1,53 @@
+package scenario
+
+import "testing"
+
+func TestCulprit(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   string
+		file  string
+		times int
+	}{
+		{
+			name: "no changes",
+			log:  "building...\nrunning...\n",
+		},
+		{
+			name:  "most reported file",
+			log:   "main.go has changed\nbuilding...\nmessages_gen.go has changed\nmessages_gen.go has changed\n",
+			file:  "messages_gen.go",
+			times: 2,
+		},
+		{
+			name:  "tie goes to the first",
+			log:   "b.go has changed\na.go has changed\n",
+			file:  "b.go",
+			times: 1,
+		},
+		{
+			name:  "log time prefix",
+			log:   "[15:04:05] messages_gen.go has changed\n[15:04:06] building...\n[15:04:07] messages_gen.go has changed\nmessages_gen.go has changed\n",
+			file:  "messages_gen.go",
+			times: 3,
+		},
+		{
+			name:  "indented and with a path",
+			log:   "  internal/gen/out.go has changed  \n",
+			file:  "internal/gen/out.go",
+			times: 1,
+		},
+		{
+			name: "suffix alone names no file",
+			log:  "[15:04:05]  has changed\n has changed\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, times := culprit(tt.log)
+			if file != tt.file || times != tt.times {
+				t.Errorf("culprit = %q, %d, want %q, %d", file, times, tt.file, tt.times)
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+// Package scenario parses the scenario.toml kept next to an example's
+// .air.toml and executes it with the runner.
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/air-verse/air-reproducible-example/internal/catalog"
+	"github.com/air-verse/air-reproducible-example/internal/editsave"
+	"github.com/air-verse/air-reproducible-example/reprokit"
+)
+
+// FileName is the scenario file looked up in every example directory.
+const FileName = "scenario.toml"
+
+// BuildMarker is the line air logs whenever it starts a build.
+const BuildMarker = "building..."
+
+// ReadyLine starts the line reprokit prints once an example's server accepts
+// connections.
+const ReadyLine = reprokit.ReadyPrefix
+
+// reprokitModule is the module path examples require to use reprokit.
+const reprokitModule = "github.com/air-verse/air-reproducible-example/reprokit"
+
+// Scenario is the machine-readable "expected vs actual" of one example.
+// Its strings may use ${PORT} and ${PROXY_PORT} for the ports the runner
+// leased for this run.
+type Scenario struct {
+	// Description says in one line what the scenario checks.
+	Description string `toml:"description"`
+	// Ready is the log line the app prints once it is serving. Startup
+	// waits for it, and rebuild expectations count it. Examples whose go.mod
+	// requires reprokit default to its READY line; ready = "" opts out.
+	Ready string `toml:"ready"`
+	// ReadyTimeout bounds the wait for Ready; 2m when unset, which leaves
+	// room for the first build to download modules.
+	ReadyTimeout Duration `toml:"ready_timeout"`
+	// StartupBug, when set, turns a failed startup into a BUG verdict with
+	// this message instead of an ERROR.
+	StartupBug string `toml:"startup_bug"`
+	// Args are passed to air, e.g. ["-c", ".air.toml"].
+	Args []string `toml:"args"`
+	// Env is added to the environment of air and the app.
+	Env []string `toml:"env"`
+	// Clean lists paths removed before air starts, relative to the example
+	// unless absolute.
+	Clean []string `toml:"clean"`
+	// Editors runs the scenario once per editor save strategy (vim, vscode,
+	// jetbrains, gofmt, git); see package editsave.
+	Editors []string `toml:"editors"`
+	// Configs runs the scenario once per air config file, passed with -c
+	// in place of any in Args.
+	Configs []string `toml:"configs"`
+	// FreshCheck, when false, skips the check that ends every scenario of
+	// a reprokit example: the app must end up running the sources on disk.
+	FreshCheck *bool  `toml:"fresh_check"`
+	Steps      []Step `toml:"step"`
+
+	// Platforms restricts the scenario to these GOOS values and Ports are
+	// the default port per role; both come from the example's meta.toml.
+	// In the scenario ExecuteFunc hands to its check, Ports are the leased
+	// ones.
+	Platforms []string       `toml:"-"`
+	Ports     map[string]int `toml:"-"`
+
+	// Dir is the example directory the scenario was loaded from.
+	Dir string `toml:"-"`
+
+	// reprokit is set when the example's go.mod requires reprokit, and
+	// infoURL is its /__repro/info on the leased app port.
+	reprokit bool
+	infoURL  string
+	// editor is the strategy and config the air config file of this
+	// variant; see Variants.
+	editor editsave.Strategy
+	config string
+	// watcher, when set, overrides the watcher of the example's config;
+	// see WithWatcher.
+	watcher *Watcher
+}
+
+// Step is either an action (edit a file, wait, send keys) whose failure is
+// an ERROR, or an expectation whose failure means the bug reproduced.
+type Step struct {
+	// Actions.
+	Touch   string   `toml:"touch"`
+	Append  string   `toml:"append"`
+	Write   string   `toml:"write"`
+	Replace string   `toml:"replace"`
+	Sleep   Duration `toml:"sleep"`
+	Settle  Duration `toml:"settle"`
+	Keys    string   `toml:"keys"`
+	WaitLog string   `toml:"wait_log"`
+
+	// Expectations.
+	ExpectRebuild   Duration `toml:"expect_rebuild"`
+	ExpectNoRebuild Duration `toml:"expect_no_rebuild"`
+	ExpectNoLoop    Duration `toml:"expect_no_loop"`
+	ExpectFresh     Duration `toml:"expect_fresh"`
+	ExpectLog       string   `toml:"expect_log"`
+	ExpectNoLog     string   `toml:"expect_no_log"`
+	Get             string   `toml:"get"`
+
+	// Arguments.
+	Text        string   `toml:"text"`
+	Pattern     string   `toml:"pattern"`
+	With        string   `toml:"with"`
+	Count       int      `toml:"count"`
+	Contains    string   `toml:"contains"`
+	NotContains string   `toml:"not_contains"`
+	Within      Duration `toml:"within"`
+	// Editor saves the file of an edit step like this editor instead of
+	// the scenario's.
+	Editor string `toml:"editor"`
+	// Config limits the step to the variant running this entry of the
+	// scenario's Configs.
+	Config string `toml:"config"`
+	// Once makes get issue a single request instead of retrying until
+	// Within passes.
+	Once bool `toml:"once"`
+	// Bug is the message reported when an expectation fails.
+	Bug string `toml:"bug"`
+}
+
+// Duration is a time.Duration written as a string ("3s", "500ms").
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Kind names the action or expectation a step performs.
+func (st Step) Kind() string {
+	kinds := st.kinds()
+	if len(kinds) != 1 {
+		return ""
+	}
+	return kinds[0]
+}
+
+func (st Step) kinds() []string {
+	var kinds []string
+	add := func(set bool, kind string) {
+		if set {
+			kinds = append(kinds, kind)
+		}
+	}
+	add(st.Touch != "", "touch")
+	add(st.Append != "", "append")
+	add(st.Write != "", "write")
+	add(st.Replace != "", "replace")
+	add(st.Sleep.Duration > 0, "sleep")
+	add(st.Settle.Duration > 0, "settle")
+	add(st.Keys != "", "keys")
+	add(st.WaitLog != "", "wait_log")
+	add(st.ExpectRebuild.Duration > 0, "expect_rebuild")
+	add(st.ExpectNoRebuild.Duration > 0, "expect_no_rebuild")
+	add(st.ExpectNoLoop.Duration > 0, "expect_no_loop")
+	add(st.ExpectFresh.Duration > 0, "expect_fresh")
+	add(st.ExpectLog != "", "expect_log")
+	add(st.ExpectNoLog != "", "expect_no_log")
+	add(st.Get != "", "get")
+	return kinds
+}
+
+// Applies reports whether the scenario should run on this platform.
+func (sc *Scenario) Applies() bool {
+	return len(sc.Platforms) == 0 || slices.Contains(sc.Platforms, runtime.GOOS)
+}
+
+// Name is the example directory name, followed by the editor and config of
+// a variant and the forced watcher, if any.
+func (sc *Scenario) Name() string {
+	tags := sc.Variant()
+	if sc.watcher != nil {
+		tags = strings.TrimSpace(tags + " " + sc.watcher.String())
+	}
+	if tags == "" {
+		return filepath.Base(sc.Dir)
+	}
+	return filepath.Base(sc.Dir) + " [" + tags + "]"
+}
+
+// Variant is the editor and config of a variant separated by a space,
+// e.g. "remote .air.follow.toml", or "" for a scenario without variants.
+func (sc *Scenario) Variant() string {
+	var tags []string
+	if sc.editor != "" {
+		tags = append(tags, string(sc.editor))
+	}
+	if sc.config != "" {
+		tags = append(tags, sc.config)
+	}
+	return strings.Join(tags, " ")
+}
+
+// Variants returns the scenario once per combination of Editors and
+// Configs, each saving its edits like that editor and starting air with
+// that config, or just the scenario when both are empty. Variants edit the
+// same files, so they must not run at the same time.
+func (sc *Scenario) Variants() []*Scenario {
+	if len(sc.Editors) == 0 && len(sc.Configs) == 0 {
+		return []*Scenario{sc}
+	}
+	editors, configs := sc.Editors, sc.Configs
+	if len(editors) == 0 {
+		editors = []string{""}
+	}
+	if len(configs) == 0 {
+		configs = []string{""}
+	}
+	var variants []*Scenario
+	for _, e := range editors {
+		for _, c := range configs {
+			v := *sc
+			v.editor = editsave.Strategy(e)
+			if c != "" {
+				v.config = c
+				v.Args = withConfig(sc.Args, c)
+			}
+			variants = append(variants, &v)
+		}
+	}
+	return variants
+}
+
+// withConfig returns args with -c set to config, replacing an existing -c.
+func withConfig(args []string, config string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" {
+			i++
+			continue
+		}
+		out = append(out, args[i])
+	}
+	return append([]string{"-c", config}, out...)
+}
+
+// WithConfig returns a copy of the scenario that runs air with the config
+// file name instead of the example's .air.toml.
+func (sc *Scenario) WithConfig(name string) *Scenario {
+	v := *sc
+	v.config = name
+	v.Args = withConfig(sc.Args, name)
+	return &v
+}
+
+// skips reports whether the variant leaves st out because the step belongs
+// to another config.
+func (sc *Scenario) skips(st Step) bool {
+	return st.Config != "" && st.Config != sc.config
+}
+
+// Load reads dir/scenario.toml.
+func Load(dir string) (*Scenario, error) {
+	path := filepath.Join(dir, FileName)
+	var sc Scenario
+	md, err := toml.DecodeFile(path, &sc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown keys %v", path, undecoded)
+	}
+	if err := sc.init(dir, md.IsDefined("ready")); err != nil {
+		return nil, err
+	}
+	if err := sc.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &sc, nil
+}
+
+// LoadExample is Load for callers that drive air themselves through
+// ExecuteFunc: an example without a scenario.toml gives a scenario with no
+// steps.
+func LoadExample(dir string) (*Scenario, error) {
+	sc, err := Load(dir)
+	if !errors.Is(err, os.ErrNotExist) {
+		return sc, err
+	}
+	sc = &Scenario{}
+	if err := sc.init(dir, false); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// init fills in what the scenario takes from its example directory.
+func (sc *Scenario) init(dir string, readyDefined bool) error {
+	sc.Dir = dir
+	meta, err := catalog.Load(dir)
+	switch {
+	case err == nil:
+		sc.Platforms, sc.Ports = meta.Platforms, meta.Ports
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	sc.reprokit = usesReprokit(dir)
+	if !readyDefined && sc.reprokit {
+		sc.Ready = ReadyLine
+	}
+	return nil
+}
+
+// usesReprokit reports whether the example's go.mod requires reprokit.
+func usesReprokit(dir string) bool {
+	mod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	return err == nil && strings.Contains(string(mod), reprokitModule)
+}
+
+func (sc *Scenario) validate() error {
+	var errs []error
+	for i, st := range sc.Steps {
+		kinds := st.kinds()
+		switch {
+		case len(kinds) == 0:
+			errs = append(errs, fmt.Errorf("step %d: no action or expectation", i+1))
+			continue
+		case len(kinds) > 1:
+			errs = append(errs, fmt.Errorf("step %d: several kinds %v in one step", i+1, kinds))
+			continue
+		}
+		if st.Editor != "" {
+			if _, err := editsave.Parse(st.Editor); err != nil {
+				errs = append(errs, fmt.Errorf("step %d: %w", i+1, err))
+			} else if !slices.Contains([]string{"touch", "append", "write", "replace"}, kinds[0]) {
+				errs = append(errs, fmt.Errorf("step %d: editor only applies to edits, not %s", i+1, kinds[0]))
+			}
+		}
+		if st.Config != "" && !slices.Contains(sc.Configs, st.Config) {
+			errs = append(errs, fmt.Errorf("step %d: config %q is not listed in configs", i+1, st.Config))
+		}
+		switch kinds[0] {
+		case "replace":
+			if st.Pattern == "" {
+				errs = append(errs, fmt.Errorf("step %d: replace needs a pattern", i+1))
+			}
+		case "expect_rebuild", "expect_no_rebuild":
+			if sc.Ready == "" {
+				errs = append(errs, fmt.Errorf("step %d: %s needs the scenario's ready line", i+1, kinds[0]))
+			}
+		}
+	}
+	for _, e := range sc.Editors {
+		if _, err := editsave.Parse(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, c := range sc.Configs {
+		if _, err := os.Stat(filepath.Join(sc.Dir, c)); err != nil {
+			errs = append(errs, fmt.Errorf("configs: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Discover loads the scenario of every example directly under root, sorted
+// by directory name.
+func Discover(root string) ([]*Scenario, error) {
+	matches, err := filepath.Glob(filepath.Join(root, "*", FileName))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	var scenarios []*Scenario
+	for _, m := range matches {
+		sc, err := Load(filepath.Dir(m))
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, sc)
+	}
+	return scenarios, nil
+}
+
+// Select keeps the scenarios whose example name is in names; all of them
+// when names is empty.
+func Select(scenarios []*Scenario, names []string) ([]*Scenario, error) {
+	if len(names) == 0 {
+		return scenarios, nil
+	}
+	byName := map[string]*Scenario{}
+	for _, sc := range scenarios {
+		byName[sc.Name()] = sc
+	}
+	var selected []*Scenario
+	for _, name := range names {
+		sc, ok := byName[filepath.Base(filepath.Clean(name))]
+		if !ok {
+			return nil, fmt.Errorf("no %s for example %q", FileName, name)
+		}
+		selected = append(selected, sc)
+	}
+	return selected, nil
+}
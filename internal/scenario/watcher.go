@@ -0,0 +1,209 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/airconfig"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/reprokit"
+	"github.com/air-verse/air-reproducible-example/reprokit/provenance"
+)
+
+// Watcher forces the file watcher air uses for a run, whatever the
+// example's config says.
+type Watcher struct {
+	// Poll selects air's polling watcher instead of fsnotify.
+	Poll bool
+	// Interval is the poll_interval; air raises anything below 500ms to
+	// 500ms and uses that when it is zero.
+	Interval time.Duration
+}
+
+func (w Watcher) String() string {
+	if w.Poll {
+		return "poll"
+	}
+	return "fsnotify"
+}
+
+// WithWatcher returns a copy of the scenario that runs air with w. Its
+// Name ends in the watcher.
+func (sc *Scenario) WithWatcher(w Watcher) *Scenario {
+	v := *sc
+	v.watcher = &w
+	return &v
+}
+
+// The poll settings of the build table can be written three ways, and
+// apply keeps to the one the config uses:
+//
+//	[build]
+//	  poll = true
+//
+//	build.poll = true
+//
+//	build = { cmd = "go build", poll = true }
+var (
+	tableHeader = regexp.MustCompile(`^\s*(\[\[?)\s*([^\]]*?)\s*\]`)
+	pollKey     = regexp.MustCompile(`^\s*"?poll(_interval)?"?\s*=`)
+	dottedPoll  = regexp.MustCompile(`^\s*"?build"?\s*\.\s*"?poll(_interval)?"?\s*=`)
+	dottedBuild = regexp.MustCompile(`^\s*"?build"?\s*\.`)
+	inlineBuild = regexp.MustCompile(`^(\s*"?build"?\s*=\s*)\{(.*)\}`)
+)
+
+// apply sets poll and poll_interval in the build table of an .air.toml,
+// replacing any it has in place and adding a [build] table when there is
+// none.
+func (w Watcher) apply(src []byte) []byte {
+	keys := []string{fmt.Sprintf("poll = %t", w.Poll)}
+	if w.Poll && w.Interval > 0 {
+		keys = append(keys, fmt.Sprintf("poll_interval = %d", w.Interval.Milliseconds()))
+	}
+	var out []string
+	table := ""
+	// at is where in out the keys go, each starting with prefix; -1 until
+	// the build table turns up, and after an inline table took them.
+	at, prefix := -1, ""
+	for _, line := range strings.SplitAfter(string(src), "\n") {
+		code, comment := airconfig.SplitComment(line)
+		if m := tableHeader.FindStringSubmatch(code); m != nil {
+			table = strings.ReplaceAll(strings.ReplaceAll(m[2], `"`, ""), " ", "")
+			out = append(out, line)
+			if table == "build" && m[1] == "[" {
+				at, prefix = len(out), ""
+			}
+			continue
+		}
+		switch {
+		case table == "build" && pollKey.MatchString(code):
+			continue
+		case table != "":
+		case dottedPoll.MatchString(code):
+			continue
+		case dottedBuild.MatchString(code):
+			out = append(out, line)
+			at, prefix = len(out), "build."
+			continue
+		case inlineBuild.MatchString(code):
+			m := inlineBuild.FindStringSubmatch(code)
+			var entries []string
+			for _, e := range inlineEntries(m[2]) {
+				if !pollKey.MatchString(e) {
+					entries = append(entries, strings.TrimSpace(e))
+				}
+			}
+			entries = append(entries, keys...)
+			rest := code[len(m[0]):]
+			line = m[1] + "{ " + strings.Join(entries, ", ") + " }" + rest + comment
+			keys = nil
+		}
+		out = append(out, line)
+	}
+	if len(keys) == 0 {
+		return []byte(strings.Join(out, ""))
+	}
+	if at < 0 {
+		out = append(out, "\n[build]\n")
+		at = len(out)
+	}
+	if !strings.HasSuffix(out[at-1], "\n") {
+		out[at-1] += "\n"
+	}
+	var add []string
+	for _, k := range keys {
+		add = append(add, prefix+k+"\n")
+	}
+	return []byte(strings.Join(slices.Insert(out, at, add...), ""))
+}
+
+// inlineEntries splits the inside of an inline table at its top-level
+// commas, skipping strings, arrays and nested tables.
+func inlineEntries(s string) []string {
+	var entries []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				entries = append(entries, s[start:i])
+				start = i + 1
+			}
+		case '"', '\'':
+			for i++; i < len(s) && s[i] != c; i++ {
+				if c == '"' && s[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		entries = append(entries, s[start:])
+	}
+	return entries
+}
+
+// Metrics is what a run measured about air's watcher and the app it ran.
+type Metrics struct {
+	// Builds counts air's build lines and Starts the scenario's ready lines.
+	Builds int
+	Starts int
+	// Latency holds, per edit, how long until the next ready line, or the
+	// next build line when the scenario has no ready line. Edits that
+	// neither followed are counted in Missed instead.
+	Latency []time.Duration
+	Missed  int
+	// Build is the provenance the app reported when the scenario ended, nil
+	// when it reported none.
+	Build *provenance.Build
+}
+
+// Median is the median of Latency, 0 when there is none.
+func (m Metrics) Median() time.Duration {
+	if len(m.Latency) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(m.Latency)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// measure collects the Metrics of a session whose steps have run.
+func (sc *Scenario) measure(s *runner.Session) Metrics {
+	m := Metrics{Builds: s.Count(BuildMarker)}
+	marker := BuildMarker
+	if sc.Ready != "" {
+		m.Starts = s.Count(sc.Ready)
+		marker = sc.Ready
+	}
+	after := s.Times(marker)
+	for _, edit := range s.Edits() {
+		i := 0
+		for i < len(after) && after[i].Before(edit) {
+			i++
+		}
+		if i == len(after) {
+			m.Missed++
+			continue
+		}
+		m.Latency = append(m.Latency, after[i].Sub(edit))
+	}
+	if sc.infoURL == "" {
+		return m
+	}
+	if body, err := s.Get(sc.infoURL); err == nil {
+		var info reprokit.Info
+		if json.Unmarshal([]byte(body), &info) == nil && info.Build.SourceHash != "" {
+			m.Build = &info.Build
+		}
+	}
+	return m
+}
@@ -0,0 +1,84 @@
+package scenario
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestWatcherApply(t *testing.T) {
+	poll := Watcher{Poll: true, Interval: 200 * time.Millisecond}
+	tests := []struct {
+		name string
+		w    Watcher
+		src  string
+		want string
+	}{
+		{
+			name: "build table",
+			w:    poll,
+			src:  "root = \".\"\n[build] # the build\n  cmd = \"go build\"\n  poll = false\n  poll_interval = 1000 # slow\n[log]\n  poll = \"kept\"\n",
+			want: "root = \".\"\n[build] # the build\npoll = true\npoll_interval = 200\n  cmd = \"go build\"\n[log]\n  poll = \"kept\"\n",
+		},
+		{
+			name: "no build table",
+			w:    Watcher{},
+			src:  "root = \".\"\n[log]\n  time = true",
+			want: "root = \".\"\n[log]\n  time = true\n[build]\npoll = false\n",
+		},
+		{
+			name: "build header on the last line",
+			w:    Watcher{Poll: true},
+			src:  "[build]",
+			want: "[build]\npoll = true\n",
+		},
+		{
+			name: "dotted keys",
+			w:    poll,
+			src:  "root = \".\"\nbuild.cmd = \"go build\"\nbuild.poll = false\n\"build\".\"poll_interval\" = 1000\nbuild.delay = 0\n[log]\n  time = true\n",
+			want: "root = \".\"\nbuild.cmd = \"go build\"\nbuild.delay = 0\nbuild.poll = true\nbuild.poll_interval = 200\n[log]\n  time = true\n",
+		},
+		{
+			name: "inline table",
+			w:    poll,
+			src:  "build = { cmd = \"go build, poll = false\", poll = false, exclude_dir = [\"a\", \"b\"], poll_interval = 1000 } # inline\n[log]\n  time = true\n",
+			want: "build = { cmd = \"go build, poll = false\", exclude_dir = [\"a\", \"b\"], poll = true, poll_interval = 200 } # inline\n[log]\n  time = true\n",
+		},
+		{
+			name: "empty inline table",
+			w:    Watcher{},
+			src:  "build = {}\n",
+			want: "build = { poll = false }\n",
+		},
+		{
+			name: "per-OS table is left alone",
+			w:    Watcher{Poll: true},
+			src:  "[build]\n  cmd = \"go build\"\n[build.windows]\n  poll = false\n",
+			want: "[build]\npoll = true\n  cmd = \"go build\"\n[build.windows]\n  poll = false\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(tt.w.apply([]byte(tt.src)))
+			if got != tt.want {
+				t.Errorf("apply:\n got %q\nwant %q", got, tt.want)
+			}
+			var cfg struct {
+				Build struct {
+					Poll         *bool
+					PollInterval int `toml:"poll_interval"`
+				}
+			}
+			if _, err := toml.Decode(got, &cfg); err != nil {
+				t.Fatalf("result is not valid TOML: %v\n%s", err, got)
+			}
+			if cfg.Build.Poll == nil || *cfg.Build.Poll != tt.w.Poll {
+				t.Errorf("build.poll = %v, want %v", cfg.Build.Poll, tt.w.Poll)
+			}
+			if want := int(tt.w.Interval.Milliseconds()); cfg.Build.PollInterval != want {
+				t.Errorf("build.poll_interval = %d, want %d", cfg.Build.PollInterval, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package ssestream
+
+import "time"
+
+// Row lines up the i-th event of the app's stream with the proxy's.
+type Row struct {
+	Index         int
+	Direct, Proxy *Event
+	// Delay is how much later the event came through the proxy.
+	Delay time.Duration
+	// Coalesced is set when the proxy delivered the event in the same read
+	// or chunk as the one before, while the app sent them apart.
+	Coalesced bool
+	// Late is set when the proxy's copy is more than the allowed delay
+	// behind, or never came.
+	Late bool
+}
+
+// Comparison is the result of Compare.
+type Comparison struct {
+	Rows      []Row
+	Coalesced int
+	Late      int
+}
+
+// OK reports whether the proxy forwarded every event on its own and in
+// time.
+func (c Comparison) OK() bool {
+	return c.Coalesced == 0 && c.Late == 0
+}
+
+// Compare lines up the events of the app's stream, direct, with those of
+// the same stream read through a proxy at the same time, and flags events
+// the proxy delivered together or more than maxDelay late.
+func Compare(direct, proxy *Stream, maxDelay time.Duration) Comparison {
+	var c Comparison
+	for i := range direct.Events {
+		r := Row{Index: i, Direct: &direct.Events[i]}
+		if i < len(proxy.Events) {
+			r.Proxy = &proxy.Events[i]
+			r.Delay = r.Proxy.At - r.Direct.At
+			r.Late = r.Delay > maxDelay
+			if i > 0 {
+				r.Coalesced = together(proxy.Events[i-1], proxy.Events[i]) &&
+					!together(direct.Events[i-1], direct.Events[i])
+			}
+		} else {
+			r.Late = true
+		}
+		if r.Coalesced {
+			c.Coalesced++
+		}
+		if r.Late {
+			c.Late++
+		}
+		c.Rows = append(c.Rows, r)
+	}
+	return c
+}
+
+// together reports whether two events arrived in one read or one chunk.
+func together(a, b Event) bool {
+	return a.Read == b.Read || (a.Chunk >= 0 && a.Chunk == b.Chunk)
+}
@@ -0,0 +1,229 @@
+// Package ssestream reads a Server-Sent Events stream over a plain HTTP/1.1
+// connection and records when every TCP read, every chunk of the chunked
+// transfer encoding and every event arrived. Comparing the record of an
+// app with the one through a proxy shows whether the proxy batched events
+// into bigger chunks or held them back (issue #791).
+package ssestream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Read is one read from the connection.
+type Read struct {
+	// At is the time since the request was sent.
+	At   time.Duration
+	Size int
+}
+
+// Chunk is one chunk of a chunked body, complete at At.
+type Chunk struct {
+	At   time.Duration
+	Size int
+}
+
+// Event is one event of the stream, ended by a blank line.
+type Event struct {
+	At   time.Duration
+	Text string
+	// Read and Chunk index the read and the chunk that completed the
+	// event; Chunk is -1 when the body is not chunked.
+	Read, Chunk int
+}
+
+// Stream is the record of one connection.
+type Stream struct {
+	URL    string
+	Status int
+	Header http.Header
+	// Chunked tells whether the body used the chunked transfer encoding.
+	Chunked bool
+	Reads   []Read
+	Chunks  []Chunk
+	Events  []Event
+	// Ended is when the server ended the stream, zero while it was still
+	// open.
+	Ended time.Duration
+}
+
+// Record requests rawURL and reads until n events arrived, the stream
+// ended or ctx is done. Running out of time is not an error: the record
+// then holds what made it, with Status 0 if not even the response header
+// did.
+func Record(ctx context.Context, rawURL string, n int) (*Stream, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" {
+		return nil, fmt.Errorf("%s: only http:// URLs are supported", rawURL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "80")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+	// Close the connection when ctx is cancelled without a deadline.
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	req := "GET " + u.RequestURI() + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"Accept: text/event-stream\r\n" +
+		"Cache-Control: no-cache\r\n" +
+		"\r\n"
+	start := time.Now()
+	if _, err := io.WriteString(conn, req); err != nil {
+		return nil, err
+	}
+
+	s := &Stream{URL: rawURL}
+	p := &parser{s: s}
+	buf := make([]byte, 64<<10)
+	for len(s.Events) < n {
+		m, err := conn.Read(buf)
+		if m > 0 {
+			at := time.Since(start)
+			s.Reads = append(s.Reads, Read{At: at, Size: m})
+			if perr := p.feed(buf[:m], at); perr != nil {
+				return s, perr
+			}
+			if p.done {
+				s.Ended = at
+				return s, nil
+			}
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			return s, nil
+		case errors.Is(err, io.EOF):
+			if s.Status == 0 {
+				return s, fmt.Errorf("%s: connection closed before a response", rawURL)
+			}
+			s.Ended = time.Since(start)
+			return s, nil
+		default:
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// parser turns the bytes of the response into the record.
+type parser struct {
+	s *Stream
+	// head collects the status line and headers until the blank line.
+	head []byte
+	// raw is chunked body data not parsed yet; chunkLeft is what is left
+	// of the current chunk's data, -1 between chunks.
+	raw       []byte
+	chunkLeft int
+	chunkSize int
+	// text is event data not ended by a blank line yet.
+	text []byte
+	done bool
+}
+
+func (p *parser) feed(b []byte, at time.Duration) error {
+	if p.s.Status == 0 {
+		p.head = append(p.head, b...)
+		i := bytes.Index(p.head, []byte("\r\n\r\n"))
+		if i < 0 {
+			return nil
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(p.head[:i+4])), nil)
+		if err != nil {
+			return err
+		}
+		p.s.Status, p.s.Header = resp.StatusCode, resp.Header
+		p.s.Chunked = len(resp.TransferEncoding) > 0 && resp.TransferEncoding[0] == "chunked"
+		p.chunkLeft = -1
+		b = p.head[i+4:]
+		p.head = nil
+	}
+	if !p.s.Chunked {
+		p.data(b, at, -1)
+		return nil
+	}
+	p.raw = append(p.raw, b...)
+	for !p.done {
+		if p.chunkLeft < 0 {
+			// A chunk starts with its size in hex and CRLF.
+			i := bytes.Index(p.raw, []byte("\r\n"))
+			if i < 0 {
+				return nil
+			}
+			line, _, _ := bytes.Cut(p.raw[:i], []byte(";"))
+			size, err := strconv.ParseInt(string(bytes.TrimSpace(line)), 16, 64)
+			if err != nil {
+				return fmt.Errorf("bad chunk size %q", p.raw[:i])
+			}
+			p.raw = p.raw[i+2:]
+			if size == 0 {
+				p.done = true
+				return nil
+			}
+			p.chunkSize, p.chunkLeft = int(size), int(size)
+		}
+		if p.chunkLeft > 0 {
+			m := min(p.chunkLeft, len(p.raw))
+			if m == 0 {
+				return nil
+			}
+			p.chunkLeft -= m
+			data := p.raw[:m]
+			p.raw = p.raw[m:]
+			chunk := len(p.s.Chunks)
+			p.data(data, at, chunk)
+		}
+		if p.chunkLeft == 0 {
+			// The chunk's data ends with CRLF.
+			if len(p.raw) < 2 {
+				return nil
+			}
+			p.raw = p.raw[2:]
+			p.s.Chunks = append(p.s.Chunks, Chunk{At: at, Size: p.chunkSize})
+			p.chunkLeft = -1
+		}
+	}
+	return nil
+}
+
+// data adds body bytes that arrived at at in chunk, recording every event
+// they complete.
+func (p *parser) data(b []byte, at time.Duration, chunk int) {
+	p.text = append(p.text, b...)
+	for {
+		i := bytes.Index(p.text, []byte("\n\n"))
+		if i < 0 {
+			return
+		}
+		p.s.Events = append(p.s.Events, Event{
+			At:    at,
+			Text:  string(p.text[:i]),
+			Read:  len(p.s.Reads) - 1,
+			Chunk: chunk,
+		})
+		p.text = p.text[i+2:]
+	}
+}
@@ -0,0 +1,244 @@
+package ssestream
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	plainHead   = "HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\n\r\n"
+	chunkedHead = "HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\nTransfer-Encoding: chunked\r\n\r\n"
+)
+
+func TestParser(t *testing.T) {
+	tests := []struct {
+		name string
+		// reads are fed one by one, the i-th at i milliseconds.
+		reads []string
+		// events are "text read chunk", chunks "size@read".
+		events []string
+		chunks []string
+		done   bool
+	}{
+		{
+			name:   "unchunked body",
+			reads:  []string{plainHead + "data: a\n\n", "data: b\n\ndata: c\n\n"},
+			events: []string{"data: a 0 -1", "data: b 1 -1", "data: c 1 -1"},
+		},
+		{
+			name:   "unchunked event split across reads",
+			reads:  []string{plainHead + "data:", " a\n", "\n"},
+			events: []string{"data: a 2 -1"},
+		},
+		{
+			name:   "header split across reads",
+			reads:  []string{"HTTP/1.1 200 OK\r\nTransfer-Enc", "oding: chunked\r\n\r", "\n9\r\ndata: a\n\n\r\n"},
+			events: []string{"data: a 2 0"},
+			chunks: []string{"9@2"},
+		},
+		{
+			name:   "one event per chunk",
+			reads:  []string{chunkedHead + "9\r\ndata: a\n\n\r\n", "9\r\ndata: b\n\n\r\n"},
+			events: []string{"data: a 0 0", "data: b 1 1"},
+			chunks: []string{"9@0", "9@1"},
+		},
+		{
+			name:   "two events in one chunk",
+			reads:  []string{chunkedHead + "12\r\ndata: a\n\ndata: b\n\n\r\n"},
+			events: []string{"data: a 0 0", "data: b 0 0"},
+			chunks: []string{"18@0"},
+		},
+		{
+			name:   "two chunks in one read",
+			reads:  []string{chunkedHead + "9\r\ndata: a\n\n\r\n9\r\ndata: b\n\n\r\n"},
+			events: []string{"data: a 0 0", "data: b 0 1"},
+			chunks: []string{"9@0", "9@0"},
+		},
+		{
+			name:   "event split across reads within a chunk",
+			reads:  []string{chunkedHead + "9\r\ndata:", " a\n\n\r\n"},
+			events: []string{"data: a 1 0"},
+			chunks: []string{"9@1"},
+		},
+		{
+			name:   "event split across chunks",
+			reads:  []string{chunkedHead + "5\r\ndata:\r\n", "4\r\n a\n\n\r\n"},
+			events: []string{"data: a 1 1"},
+			chunks: []string{"5@0", "4@1"},
+		},
+		{
+			name:   "size line and trailing CRLF split across reads",
+			reads:  []string{chunkedHead + "9", "\r\ndata: a\n\n", "\r", "\n"},
+			events: []string{"data: a 1 0"},
+			chunks: []string{"9@3"},
+		},
+		{
+			name:   "chunk extension",
+			reads:  []string{chunkedHead + "9;name=value\r\ndata: a\n\n\r\n"},
+			events: []string{"data: a 0 0"},
+			chunks: []string{"9@0"},
+		},
+		{
+			name:   "zero-size chunk ends the stream",
+			reads:  []string{chunkedHead + "9\r\ndata: a\n\n\r\n", "0\r\n\r\n", "9\r\ndata: b\n\n\r\n"},
+			events: []string{"data: a 0 0"},
+			chunks: []string{"9@0"},
+			done:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Stream{}
+			p := &parser{s: s}
+			for i, r := range tt.reads {
+				if p.done {
+					break
+				}
+				at := time.Duration(i) * time.Millisecond
+				s.Reads = append(s.Reads, Read{At: at, Size: len(r)})
+				if err := p.feed([]byte(r), at); err != nil {
+					t.Fatalf("read %d: %v", i, err)
+				}
+			}
+			if s.Status != 200 {
+				t.Errorf("Status = %d, want 200", s.Status)
+			}
+			if want := len(tt.chunks) > 0; s.Chunked != want {
+				t.Errorf("Chunked = %v, want %v", s.Chunked, want)
+			}
+			var events, chunks []string
+			for _, e := range s.Events {
+				if e.At != time.Duration(e.Read)*time.Millisecond {
+					t.Errorf("event %q at %s, read %d", e.Text, e.At, e.Read)
+				}
+				events = append(events, fmt.Sprintf("%s %d %d", e.Text, e.Read, e.Chunk))
+			}
+			for _, c := range s.Chunks {
+				chunks = append(chunks, fmt.Sprintf("%d@%d", c.Size, c.At/time.Millisecond))
+			}
+			if !slices.Equal(events, tt.events) {
+				t.Errorf("events = %q, want %q", events, tt.events)
+			}
+			if !slices.Equal(chunks, tt.chunks) {
+				t.Errorf("chunks = %q, want %q", chunks, tt.chunks)
+			}
+			if p.done != tt.done {
+				t.Errorf("done = %v, want %v", p.done, tt.done)
+			}
+		})
+	}
+}
+
+func TestParserBadChunkSize(t *testing.T) {
+	p := &parser{s: &Stream{}}
+	if err := p.feed([]byte(chunkedHead+"zz\r\ndata: a\n\n\r\n"), 0); err == nil {
+		t.Error("feed accepted chunk size zz")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ms := time.Millisecond
+	// ev builds an event at milliseconds into the stream, in read and chunk.
+	ev := func(at time.Duration, read, chunk int) Event {
+		return Event{At: at * ms, Read: read, Chunk: chunk}
+	}
+	tests := []struct {
+		name          string
+		direct, proxy []Event
+		// rows are "coalesced late" per direct event, "C" and "L" for set.
+		rows []string
+	}{
+		{
+			name:   "forwarded one by one",
+			direct: []Event{ev(0, 0, 0), ev(100, 1, 1), ev(200, 2, 2)},
+			proxy:  []Event{ev(5, 0, 0), ev(105, 1, 1), ev(205, 2, 2)},
+			rows:   []string{"-", "-", "-"},
+		},
+		{
+			name:   "coalesced into one read",
+			direct: []Event{ev(0, 0, -1), ev(100, 1, -1), ev(200, 2, -1)},
+			proxy:  []Event{ev(200, 0, -1), ev(200, 0, -1), ev(200, 0, -1)},
+			rows:   []string{"L", "C", "C"},
+		},
+		{
+			name:   "coalesced into one chunk over several reads",
+			direct: []Event{ev(0, 0, 0), ev(100, 1, 1)},
+			proxy:  []Event{ev(0, 0, 0), ev(100, 1, 0)},
+			rows:   []string{"-", "C"},
+		},
+		{
+			name:   "together in the app too",
+			direct: []Event{ev(0, 0, 0), ev(0, 0, 0)},
+			proxy:  []Event{ev(0, 0, 0), ev(0, 0, 0)},
+			rows:   []string{"-", "-"},
+		},
+		{
+			name:   "unchunked reads apart are not together",
+			direct: []Event{ev(0, 0, -1), ev(100, 1, -1)},
+			proxy:  []Event{ev(0, 0, -1), ev(100, 1, -1)},
+			rows:   []string{"-", "-"},
+		},
+		{
+			name:   "late",
+			direct: []Event{ev(0, 0, 0), ev(100, 1, 1)},
+			proxy:  []Event{ev(50, 0, 0), ev(1100, 1, 1)},
+			rows:   []string{"-", "L"},
+		},
+		{
+			name:   "missing events",
+			direct: []Event{ev(0, 0, 0), ev(100, 1, 1), ev(200, 2, 2)},
+			proxy:  []Event{ev(0, 0, 0)},
+			rows:   []string{"-", "L", "L"},
+		},
+		{
+			name:   "extra proxy events are ignored",
+			direct: []Event{ev(0, 0, 0)},
+			proxy:  []Event{ev(0, 0, 0), ev(0, 0, 0)},
+			rows:   []string{"-"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Compare(&Stream{Events: tt.direct}, &Stream{Events: tt.proxy}, 100*ms)
+			var rows []string
+			coalesced, late := 0, 0
+			for i, r := range c.Rows {
+				s := ""
+				if r.Coalesced {
+					s += "C"
+					coalesced++
+				}
+				if r.Late {
+					s += "L"
+					late++
+				}
+				if s == "" {
+					s = "-"
+				}
+				rows = append(rows, s)
+				if r.Index != i || *r.Direct != tt.direct[i] {
+					t.Errorf("row %d: index %d, direct event %+v", i, r.Index, *r.Direct)
+				}
+				switch {
+				case i >= len(tt.proxy):
+					if r.Proxy != nil {
+						t.Errorf("row %d has a proxy event", i)
+					}
+				case r.Delay != tt.proxy[i].At-tt.direct[i].At:
+					t.Errorf("row %d delay = %s", i, r.Delay)
+				}
+			}
+			if !slices.Equal(rows, tt.rows) {
+				t.Errorf("rows = %q, want %q", rows, tt.rows)
+			}
+			if c.Coalesced != coalesced || c.Late != late {
+				t.Errorf("Coalesced, Late = %d, %d, want %d, %d", c.Coalesced, c.Late, coalesced, late)
+			}
+			if want := coalesced == 0 && late == 0; c.OK() != want {
+				t.Errorf("OK = %v, want %v", c.OK(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Package stress fills an example with a large generated file tree and
+// measures how air copes with it: startup time, inotify watches, memory and
+// idle CPU of the air process, and whether edits inside excluded
+// directories trigger rebuilds.
+package stress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestFile records the generated trees of an example so Clean can
+// remove exactly those.
+const ManifestFile = ".stress.json"
+
+// Tree is one generated directory tree.
+type Tree struct {
+	// Name is the kind of tree: node_modules, vendor, .git or nested.
+	Name string `json:"name"`
+	// Root is the generated directory relative to the example, with
+	// forward slashes. It sits below the directory the tree is named after,
+	// so content that was already there is left alone.
+	Root string `json:"root"`
+	// Files and Dirs count what was generated.
+	Files int `json:"files"`
+	Dirs  int `json:"dirs"`
+	// Probe is a .go file in the deepest directory, edited by Measure.
+	Probe string `json:"probe"`
+}
+
+// kind describes how a tree is laid out.
+type kind struct {
+	name  string
+	root  string
+	share int // percent of the files
+	// dir names a directory level, file a file in a directory.
+	dir  func(level, i int) string
+	file func(i int) string
+}
+
+var kinds = []kind{
+	{
+		name: "node_modules", root: "node_modules/stress", share: 40,
+		// Packages nest their own node_modules, like npm before v3.
+		dir: func(level, i int) string {
+			if level == 0 {
+				return fmt.Sprintf("pkg-%d", i)
+			}
+			return fmt.Sprintf("node_modules/pkg-%d", i)
+		},
+		file: func(i int) string {
+			if i%4 == 0 {
+				return fmt.Sprintf("bindings%d.go", i)
+			}
+			return fmt.Sprintf("index%d.js", i)
+		},
+	},
+	{
+		name: "vendor", root: "vendor/stress.example.com", share: 20,
+		dir:  func(level, i int) string { return fmt.Sprintf("mod%d", i) },
+		file: func(i int) string { return fmt.Sprintf("file%d.go", i) },
+	},
+	{
+		name: ".git", root: ".git/stress-objects", share: 20,
+		dir:  func(level, i int) string { return fmt.Sprintf("%02x", i) },
+		file: func(i int) string { return fmt.Sprintf("%038x", i) },
+	},
+	{
+		name: "nested", root: "pkg/stress", share: 20,
+		dir:  func(level, i int) string { return fmt.Sprintf("p%d", i) },
+		file: func(i int) string { return fmt.Sprintf("f%d.go", i) },
+	},
+}
+
+// Spec sizes the generated trees.
+type Spec struct {
+	// Files is the total number of files, split between the trees.
+	Files int
+	// Depth is how many directory levels each tree has below its root.
+	Depth int
+	// PerDir is the number of files in each directory.
+	PerDir int
+}
+
+// Generate creates the trees in dir and writes the manifest. It fails if
+// dir already has one; call Clean first.
+func Generate(dir string, spec Spec) ([]Tree, error) {
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+		return nil, fmt.Errorf("%s already has generated trees; clean them first", dir)
+	}
+	if spec.PerDir <= 0 {
+		spec.PerDir = 20
+	}
+	if spec.Depth <= 0 {
+		spec.Depth = 1
+	}
+	var trees []Tree
+	for _, k := range kinds {
+		t, err := generate(dir, k, spec.Files*k.share/100, spec)
+		if t.Root != "" {
+			trees = append(trees, t)
+		}
+		if err != nil {
+			writeManifest(dir, trees)
+			return trees, err
+		}
+	}
+	return trees, writeManifest(dir, trees)
+}
+
+func generate(dir string, k kind, files int, spec Spec) (Tree, error) {
+	t := Tree{Name: k.name, Root: k.root}
+	root := filepath.Join(dir, filepath.FromSlash(k.root))
+	if _, err := os.Stat(root); err == nil {
+		return Tree{}, fmt.Errorf("%s already exists", root)
+	}
+	pkg := strings.NewReplacer("-", "", ".", "").Replace(filepath.Base(k.root))
+	dirs := max((files+spec.PerDir-1)/spec.PerDir, 1)
+	// fanout^Depth >= dirs, so the directories spread over every level.
+	fanout := 2
+	for pow(fanout, spec.Depth) < dirs {
+		fanout++
+	}
+	var deepest string
+	for d := 0; d < dirs; d++ {
+		parts := []string{root}
+		for level, n := 0, d; level < spec.Depth; level++ {
+			parts = append(parts, k.dir(level, n%fanout))
+			n /= fanout
+		}
+		p := filepath.Join(parts...)
+		if err := os.MkdirAll(p, 0o755); err != nil {
+			return t, err
+		}
+		t.Dirs++
+		deepest = p
+		for i := 0; i < spec.PerDir && t.Files < files; i++ {
+			name := k.file(t.Files)
+			content := fmt.Sprintf("// generated by repro stress (%s %d)\n", k.name, t.Files)
+			if strings.HasSuffix(name, ".go") {
+				content = fmt.Sprintf("package %s\n\n%s", pkg, content)
+			}
+			if err := os.WriteFile(filepath.Join(p, name), []byte(content), 0o644); err != nil {
+				return t, err
+			}
+			t.Files++
+		}
+	}
+	probe := filepath.Join(deepest, "probe.go")
+	if err := os.WriteFile(probe, []byte("package "+pkg+"\n"), 0o644); err != nil {
+		return t, err
+	}
+	t.Files++
+	rel, _ := filepath.Rel(dir, probe)
+	t.Probe = filepath.ToSlash(rel)
+	return t, nil
+}
+
+func pow(b, e int) int {
+	n := 1
+	for range e {
+		n *= b
+	}
+	return n
+}
+
+func writeManifest(dir string, trees []Tree) error {
+	data, err := json.MarshalIndent(trees, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o644)
+}
+
+// Trees reads the manifest of dir.
+func Trees(dir string) ([]Tree, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var trees []Tree
+	return trees, json.Unmarshal(data, &trees)
+}
+
+// Clean removes the trees listed in the manifest of dir, the directories
+// above them that end up empty, and the manifest. A directory without a
+// manifest is left alone.
+func Clean(dir string) error {
+	trees, err := Trees(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, t := range trees {
+		p := filepath.Join(dir, filepath.FromSlash(t.Root))
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+		// os.Remove fails on directories that still hold something.
+		for parent := filepath.Dir(p); parent != filepath.Clean(dir); parent = filepath.Dir(parent) {
+			if os.Remove(parent) != nil {
+				break
+			}
+		}
+	}
+	return os.Remove(filepath.Join(dir, ManifestFile))
+}
@@ -0,0 +1,125 @@
+package stress
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/airconfig"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+)
+
+// runningMarker is the line air logs when it starts the app, waited for
+// when the scenario has no ready line.
+const runningMarker = "running..."
+
+// Result is what Measure found.
+type Result struct {
+	// Startup is the time from starting air to the app's first ready line.
+	Startup time.Duration
+	// Watches is the number of inotify watches air holds once idle; -1
+	// when it cannot be read.
+	Watches int
+	// RSS is air's resident memory in bytes once idle.
+	RSS int64
+	// IdleCPU is the CPU air used while nothing changed, in percent of one
+	// core.
+	IdleCPU float64
+	// Edits holds the probe edit of every tree.
+	Edits []Edit
+}
+
+// Edit is the outcome of editing a tree's probe file.
+type Edit struct {
+	Tree Tree
+	// Watched is whether the air config makes air watch the probe's
+	// directory, i.e. whether a rebuild is expected.
+	Watched bool
+	Rebuilt bool
+}
+
+// Options tunes Measure.
+type Options struct {
+	// Idle is how long to sample CPU while nothing changes.
+	Idle time.Duration
+	// Quiet is how long an edit in an excluded tree is given to cause a
+	// rebuild anyway; it should exceed the config's build delay.
+	Quiet time.Duration
+}
+
+// Measure runs air on the example of sc, whose generated trees are listed
+// in its manifest, and measures it.
+func Measure(sc *scenario.Scenario, airBin string, opts Options) (runner.Result, *Result) {
+	r := &Result{Watches: -1}
+	res := scenario.ExecuteFunc(sc, airBin, func(run *scenario.Scenario, s *runner.Session) error {
+		return r.measure(run, s, opts)
+	})
+	return res, r
+}
+
+func (r *Result) measure(run *scenario.Scenario, s *runner.Session, opts Options) error {
+	trees, err := Trees(run.Dir)
+	if err != nil {
+		return fmt.Errorf("read the generated trees: %w", err)
+	}
+	args := run.Args
+	cfg := filepath.Join(run.Dir, airconfig.FileName)
+	for i, a := range args {
+		if a == "-c" && i+1 < len(args) {
+			cfg = filepath.Join(run.Dir, args[i+1])
+		}
+	}
+	rules, err := airconfig.LoadWatch(cfg)
+	if err != nil {
+		return err
+	}
+
+	ready := run.Ready
+	if ready == "" {
+		ready = runningMarker
+	}
+	if err := s.WaitLog(ready, 1, 5*time.Minute); err != nil {
+		return fmt.Errorf("startup: %w", err)
+	}
+	if t := s.Times(ready); len(t) > 0 {
+		r.Startup = t[0].Sub(s.Started())
+	}
+	if err := s.Settle(2*time.Second, time.Minute); err != nil {
+		return err
+	}
+
+	before, err := readProc(s.Pid())
+	if err != nil {
+		return err
+	}
+	if err := s.Sleep(opts.Idle); err != nil {
+		return err
+	}
+	after, err := readProc(s.Pid())
+	if err != nil {
+		return err
+	}
+	r.Watches, r.RSS = after.watches, after.rss
+	r.IdleCPU = float64(after.cpu-before.cpu) / float64(opts.Idle) * 100
+
+	for _, t := range trees {
+		probe := filepath.Join(run.Dir, filepath.FromSlash(t.Probe))
+		e := Edit{Tree: t, Watched: rules.Watched(filepath.Dir(probe))}
+		builds := s.Count(scenario.BuildMarker)
+		if err := s.AppendFile(t.Probe, "// edited by repro stress\n", ""); err != nil {
+			return err
+		}
+		wait := opts.Quiet
+		if e.Watched {
+			wait = time.Minute
+		}
+		e.Rebuilt = s.WaitLog(scenario.BuildMarker, builds+1, wait) == nil
+		if e.Rebuilt {
+			// Let the build finish before the next edit.
+			s.Settle(2*time.Second, time.Minute)
+		}
+		r.Edits = append(r.Edits, e)
+	}
+	return nil
+}
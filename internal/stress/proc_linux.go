@@ -0,0 +1,71 @@
+package stress
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userHZ is the unit of the CPU times in /proc/<pid>/stat. It is 100 on
+// every Linux platform Go supports.
+const userHZ = 100
+
+type procStats struct {
+	watches int
+	rss     int64
+	// cpu is user plus system time.
+	cpu time.Duration
+}
+
+// readProc reads the stats of process pid from /proc.
+func readProc(pid int) (procStats, error) {
+	var st procStats
+	dir := fmt.Sprintf("/proc/%d", pid)
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return st, err
+	}
+	// The command name in parentheses may contain spaces; the fields
+	// after it are fixed: utime and stime are the 12th and 13th.
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	if len(fields) < 13 {
+		return st, fmt.Errorf("%s/stat: too few fields", dir)
+	}
+	utime, _ := strconv.ParseInt(fields[11], 10, 64)
+	stime, _ := strconv.ParseInt(fields[12], 10, 64)
+	st.cpu = time.Duration(utime+stime) * time.Second / userHZ
+
+	status, err := os.Open(filepath.Join(dir, "status"))
+	if err != nil {
+		return st, err
+	}
+	defer status.Close()
+	sc := bufio.NewScanner(status)
+	for sc.Scan() {
+		if kb, ok := strings.CutPrefix(sc.Text(), "VmRSS:"); ok {
+			n, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(kb), " kB"), 10, 64)
+			st.rss = n * 1024
+		}
+	}
+
+	// Every inotify watch is an "inotify wd:" line in the fdinfo of the
+	// inotify descriptor that holds it.
+	infos, err := filepath.Glob(filepath.Join(dir, "fdinfo", "*"))
+	if err != nil {
+		return st, err
+	}
+	for _, f := range infos {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		st.watches += bytes.Count(data, []byte("inotify wd:"))
+	}
+	return st, nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package stress
+
+import (
+	"errors"
+	"time"
+)
+
+type procStats struct {
+	watches int
+	rss     int64
+	cpu     time.Duration
+}
+
+func readProc(pid int) (procStats, error) {
+	return procStats{}, errors.New("stress measurements read /proc and need Linux")
+}
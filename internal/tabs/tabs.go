@@ -0,0 +1,237 @@
+// Package tabs opens a page in several tabs of an emulated browser, to
+// measure what a browser's connection limit does to them (issue #754).
+//
+// The tabs share one connection pool with at most six connections per
+// host, as in Chrome and Firefox over HTTP/1.1, and every tab keeps open
+// the EventSource its page opens, as Air's injected script does. Once
+// streams hold every connection, the next page waits for one that never
+// frees. Over HTTP/2 all requests share one connection and the limit does
+// not apply.
+package tabs
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// DefaultPerHost is the per-host connection limit of browsers over
+// HTTP/1.1.
+const DefaultPerHost = 6
+
+// Options configure Open.
+type Options struct {
+	// Tabs is the number of tabs opened one after the other.
+	Tabs int
+	// PerHost limits the connections to the host; DefaultPerHost when
+	// zero.
+	PerHost int
+	// Shared opens the event stream of a page that starts a SharedWorker
+	// once for all tabs, like a browser running the worker. Without it the
+	// browser lacks SharedWorker and every tab opens its own EventSource,
+	// which is what Air's script falls back to.
+	Shared bool
+	// HTTP1 keeps https:// pages on HTTP/1.1 instead of HTTP/2.
+	HTTP1 bool
+	// Wait bounds each page load, and how long a tab waits for its event
+	// stream to answer before the next tab opens; 5s when zero.
+	Wait time.Duration
+}
+
+// Tab is what happened in one tab.
+type Tab struct {
+	// Index counts tabs from 1, as the issue does.
+	Index int
+	// Status and Proto are those of the page's response.
+	Status int
+	Proto  string
+	// TTFB is the time from asking for the page to its response header,
+	// including the wait for a free connection; Load is the time to the
+	// end of the body.
+	TTFB, Load time.Duration
+	// Stream is the event stream this tab opened, empty when the page has
+	// none or the tab shares one opened before.
+	Stream string
+	// StreamTTFB is the time to the stream's response header, zero while
+	// it is still waiting for a connection.
+	StreamTTFB time.Duration
+	// Err is set when the page did not load, e.g. within Wait.
+	Err error
+}
+
+// Browser holds the tabs and their open streams until Close.
+type Browser struct {
+	Tabs      []Tab
+	client    *http.Client
+	transport *http.Transport
+	cancel    context.CancelFunc
+}
+
+// Close closes every stream and connection.
+func (b *Browser) Close() {
+	b.cancel()
+	b.transport.CloseIdleConnections()
+}
+
+var (
+	// eventSource finds the stream a page or worker script opens.
+	eventSource = regexp.MustCompile(`new EventSource\(\s*["'` + "`" + `]([^"'` + "`" + `]+)`)
+	// sharedWorker finds the worker script a page starts.
+	sharedWorker = regexp.MustCompile(`new SharedWorker\(\s*["'` + "`" + `]([^"'` + "`" + `]+)`)
+)
+
+// Open opens pageURL in opts.Tabs tabs one after the other and leaves the
+// streams they open running until the returned Browser is closed. Page
+// loads that fail are recorded in their Tab; the error is for bad input.
+func Open(ctx context.Context, pageURL string, opts Options) (*Browser, error) {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if page.Scheme != "http" && page.Scheme != "https" {
+		return nil, fmt.Errorf("%s: not an http:// or https:// URL", pageURL)
+	}
+	if opts.PerHost == 0 {
+		opts.PerHost = DefaultPerHost
+	}
+	if opts.Wait == 0 {
+		opts.Wait = 5 * time.Second
+	}
+	t := &http.Transport{
+		MaxConnsPerHost:     opts.PerHost,
+		MaxIdleConnsPerHost: opts.PerHost,
+		// Examples serve a certificate made at startup.
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: !opts.HTTP1,
+	}
+	if opts.HTTP1 {
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	b := &Browser{client: &http.Client{Transport: t}, transport: t, cancel: cancel}
+
+	var shared bool
+	for i := 1; i <= opts.Tabs; i++ {
+		tab, body := b.load(ctx, page, opts.Wait)
+		tab.Index = i
+		if tab.Err == nil {
+			stream := streamOf(body)
+			if opts.Shared {
+				if worker := sharedWorker.FindStringSubmatch(body); worker != nil {
+					stream = ""
+					if !shared {
+						shared = true
+						stream = b.workerStream(ctx, page.ResolveReference(ref(worker[1])), opts.Wait)
+					}
+				}
+			}
+			if stream != "" {
+				tab.Stream = page.ResolveReference(ref(stream)).String()
+				tab.StreamTTFB = b.stream(ctx, tab.Stream, opts.Wait)
+			}
+		}
+		b.Tabs = append(b.Tabs, tab)
+	}
+	return b, nil
+}
+
+// load requests the page as a new tab does.
+func (b *Browser) load(ctx context.Context, page *url.URL, wait time.Duration) (Tab, string) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	var tab Tab
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, page.String(), nil)
+	if err != nil {
+		tab.Err = err
+		return tab, ""
+	}
+	req.Header.Set("Accept", "text/html")
+	start := time.Now()
+	resp, err := b.client.Do(req)
+	tab.TTFB = time.Since(start)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("no response within %s", wait)
+		}
+		tab.Err = err
+		return tab, ""
+	}
+	defer resp.Body.Close()
+	tab.Status, tab.Proto = resp.StatusCode, resp.Proto
+	body, err := io.ReadAll(resp.Body)
+	tab.Load = time.Since(start)
+	if err != nil {
+		tab.Err = err
+	}
+	return tab, string(body)
+}
+
+// workerStream fetches the worker script and returns the stream it opens.
+func (b *Browser) workerStream(ctx context.Context, worker *url.URL, wait time.Duration) string {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, worker.String(), nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	script, _ := io.ReadAll(resp.Body)
+	return streamOf(string(script))
+}
+
+// stream opens an event stream that stays open until the browser closes,
+// and returns how long its response header took, or zero when it did not
+// come within wait.
+func (b *Browser) stream(ctx context.Context, streamURL string, wait time.Duration) time.Duration {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return 0
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	start := time.Now()
+	answered := make(chan time.Duration, 1)
+	go func() {
+		resp, err := b.client.Do(req)
+		if err != nil {
+			answered <- 0
+			return
+		}
+		answered <- time.Since(start)
+		// Hold the connection like an open EventSource.
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	select {
+	case d := <-answered:
+		return d
+	case <-time.After(wait):
+		return 0
+	}
+}
+
+// streamOf returns the URL of the first EventSource in script.
+func streamOf(script string) string {
+	if m := eventSource.FindStringSubmatch(script); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func ref(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		return &url.URL{}
+	}
+	return u
+}
@@ -1,18 +1,34 @@
 package main
 
 import (
+	"embed"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
 	version := "v2" // Modify this value to test hot reload
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "Hello from %s at %s\n", version, time.Now().Format(time.RFC3339))
 	})
 
-	fmt.Printf("Server starting with version %s on :8080\n", version)
-	http.ListenAndServe(":8080", nil)
+	fmt.Printf("Server starting with version %s on :%s\n", version, port)
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -1,3 +1,7 @@
 module issue-197-subdir-watch
 
 go 1.21
+
+require github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+
+replace github.com/air-verse/air-reproducible-example/reprokit => ../reprokit
@@ -1,21 +1,33 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
 	startTime := time.Now().Format("15:04:05.000")
 	pid := os.Getpid()
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3000"
+	}
 
 	// These messages help identify double-build issues
 	// If you see these printed twice in quick succession, the bug is triggered
 	fmt.Printf("running... (PID: %d, started at %s)\n", pid, startTime)
-	fmt.Println("Starting the server on :3000...")
+	fmt.Printf("Starting the server on :%s...\n", port)
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "Hello! PID=%d, Started=%s\n", pid, startTime)
@@ -30,7 +42,7 @@ func main() {
 		fmt.Fprintf(w, "Build Time: %s\nPID: %d\n", startTime, pid)
 	})
 
-	if err := http.ListenAndServe(":3000", nil); err != nil {
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
 		// This error is expected when the bug triggers - two servers try to bind the same port
 		log.Fatalf("Server error: %v", err)
 	}
@@ -1,3 +1,7 @@
 module issue-505-tmp-dir-nested
 
 go 1.25.4
+
+require github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+
+replace github.com/air-verse/air-reproducible-example/reprokit => ../reprokit
@@ -1,19 +1,33 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
-	fmt.Println("Server starting on :3000...")
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3000"
+	}
+
+	fmt.Printf("Server starting on :%s...\n", port)
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "Hello! Time: %s\n", time.Now().Format(time.RFC3339))
 	})
 
-	if err := http.ListenAndServe(":3000", nil); err != nil {
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
 		fmt.Printf("Server error: %v\n", err)
 	}
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder compresses what is written to it; Flush sends what it holds.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// newEncoder returns an encoder that applies codings in order and writes
+// to w. Closing it closes every layer.
+func newEncoder(w io.Writer, codings []string) (encoder, error) {
+	var layers []encoder
+	for i := len(codings) - 1; i >= 0; i-- {
+		var e encoder
+		switch codings[i] {
+		case "gzip":
+			e = gzip.NewWriter(w)
+		case "deflate":
+			e = zlib.NewWriter(w)
+		case "br":
+			e = brotli.NewWriter(w)
+		case "zstd":
+			z, err := zstd.NewWriter(w)
+			if err != nil {
+				return nil, err
+			}
+			e = z
+		default:
+			return nil, fmt.Errorf("unknown coding %q", codings[i])
+		}
+		layers = append(layers, e)
+		w = e
+	}
+	return chain(layers), nil
+}
+
+// chain is a stack of encoders, the innermost one last.
+type chain []encoder
+
+func (c chain) Write(p []byte) (int, error) {
+	if len(c) == 0 {
+		return 0, nil
+	}
+	return c[len(c)-1].Write(p)
+}
+
+func (c chain) Flush() error {
+	for i := len(c) - 1; i >= 0; i-- {
+		if err := c[i].Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c chain) Close() error {
+	for i := len(c) - 1; i >= 0; i-- {
+		if err := c[i].Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode applies codings to body in order.
+func encode(body []byte, codings []string) ([]byte, error) {
+	if len(codings) == 0 {
+		return body, nil
+	}
+	var buf bytes.Buffer
+	enc, err := newEncoder(&buf, codings)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := enc.Write(body); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// accepts reports whether the request's Accept-Encoding lists every one
+// of codings.
+func accepts(r *http.Request, codings []string) bool {
+	accepted := map[string]bool{}
+	for _, c := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, _, _ := strings.Cut(c, ";")
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, c := range codings {
+		if !accepted[c] {
+			return false
+		}
+	}
+	return true
+}
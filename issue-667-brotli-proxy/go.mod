@@ -3,3 +3,9 @@ module issue667-brotli
 go 1.23
 
 require github.com/andybalholm/brotli v1.2.0
+
+require github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+
+require github.com/klauspost/compress v1.18.0
+
+replace github.com/air-verse/air-reproducible-example/reprokit => ../reprokit
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/andybalholm/brotli"
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 const htmlTemplate = `<!DOCTYPE html>
 <html lang="en">
 <head>
@@ -20,67 +29,150 @@ const htmlTemplate = `<!DOCTYPE html>
   <p>Content-Encoding: <code>%s</code></p>
   <p>Check page source for <code>__air_internal</code> to verify script injection.</p>
   <hr>
-  <h2>Test Links (via proxy port 3001):</h2>
-  <ul>
-    <li><a href="/plain">/plain</a> - No compression (should work)</li>
-    <li><a href="/brotli">/brotli</a> - Brotli compression (BUG: script not injected)</li>
-  </ul>
+  <h2>Test Links (open them through Air's proxy):</h2>
+%s
 </body>
 </html>`
 
+// route is one cell of the encoding matrix.
+type route struct {
+	path string
+	// codings is the Content-Encoding, in the order the codings are
+	// applied; empty for none.
+	codings []string
+	// note tells what the route tests.
+	note string
+	// vary sends Vary: Accept-Encoding. Without it the route is encoded
+	// whatever the client accepts.
+	vary bool
+	// chunked streams the page in pieces, without Content-Length.
+	chunked bool
+	// json serves a JSON document instead of a page; the proxy must pass
+	// it through untouched.
+	json bool
+}
+
+var routes = []route{
+	{path: "/plain", note: "No compression", vary: true},
+	{path: "/gzip", codings: []string{"gzip"}, note: "gzip, fixed in air-verse/air#876", vary: true},
+	{path: "/deflate", codings: []string{"deflate"}, note: "deflate (zlib)", vary: true},
+	{path: "/brotli", codings: []string{"br"}, note: "Brotli, the original report", vary: true},
+	{path: "/zstd", codings: []string{"zstd"}, note: "Zstandard", vary: true},
+	{path: "/double", codings: []string{"gzip", "br"}, note: "gzip, then Brotli on top", vary: true},
+	{path: "/no-vary", codings: []string{"gzip"}, note: "gzip without Vary, even to clients that do not accept it"},
+	{path: "/chunked", codings: []string{"gzip"}, note: "gzip streamed in chunks, without Content-Length", vary: true, chunked: true},
+	{path: "/data.json", codings: []string{"gzip"}, note: "gzip JSON holding </body>, which must not get the script", vary: true, json: true},
+}
+
 func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3000"
+	}
+	proxyPort := os.Getenv("PROXY_PORT")
+	if proxyPort == "" {
+		proxyPort = "3001"
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handleIndex)
-	mux.HandleFunc("/plain", handlePlain)
-	mux.HandleFunc("/brotli", handleBrotli)
+	for _, rt := range routes {
+		mux.HandleFunc(rt.path, rt.serve)
+	}
 
-	log.Println("Server listening on http://localhost:3000")
-	log.Println("Access via Air proxy: http://localhost:3001")
+	log.Printf("Server listening on http://localhost:%s", port)
+	log.Printf("Access via Air proxy: http://localhost:%s", proxyPort)
 	log.Println("")
 	log.Println("Test endpoints:")
-	log.Println("  http://localhost:3001/plain   - No compression (should inject script)")
-	log.Println("  http://localhost:3001/brotli  - Brotli (BUG: no script injection)")
-	if err := http.ListenAndServe(":3000", mux); err != nil {
+	for _, rt := range routes {
+		log.Printf("  http://localhost:%s%-11s - %s", proxyPort, rt.path, rt.note)
+	}
+	if err := reprokit.ListenAndServe(":"+port, mux); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// links lists the routes for the pages.
+func links() string {
+	var b strings.Builder
+	b.WriteString("  <ul>\n")
+	for _, rt := range routes {
+		fmt.Fprintf(&b, "    <li><a href=\"%s\">%s</a> - %s</li>\n", rt.path, rt.path, strings.ReplaceAll(rt.note, "<", "&lt;"))
+	}
+	b.WriteString("  </ul>")
+	return b.String()
+}
+
 func handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	fmt.Fprintf(w, `<!DOCTYPE html>
 <html lang="en">
 <head><meta charset="utf-8"><title>Issue #667 Reproduction</title></head>
 <body>
-  <h1>Issue #667 - Brotli Breaks Proxy Script Injection</h1>
-  <p>Click each link and view page source (Ctrl+U) to check for <code>__air_internal</code>:</p>
-  <ul>
-    <li><a href="/plain">/plain</a> - Expected: script injected</li>
-    <li><a href="/brotli">/brotli</a> - Expected: script NOT injected (BUG)</li>
-  </ul>
+  <h1>Issue #667 - Compression Breaks Proxy Script Injection</h1>
+  <p>Click each link and view page source (Ctrl+U) to check for <code>__air_internal</code>.
+  Every page should have it exactly once; /data.json should not have it.</p>
+%s
 </body>
-</html>`)
+</html>`, links())
 }
 
-func handlePlain(w http.ResponseWriter, r *http.Request) {
+func (rt route) serve(w http.ResponseWriter, r *http.Request) {
+	name := rt.path[1:]
+	body := fmt.Sprintf(htmlTemplate, name, name, strings.Join(rt.codings, ", "), links())
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprintf(w, htmlTemplate, "Plain", "Plain", "none")
-}
+	if rt.json {
+		body = fmt.Sprintf(`{"route": %q, "html": "<p>not a page</p></body></html>"}`+"\n", rt.path)
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if rt.vary {
+		w.Header().Set("Vary", "Accept-Encoding")
+	}
 
-func handleBrotli(w http.ResponseWriter, r *http.Request) {
-	// Check if client accepts brotli
-	acceptEncoding := r.Header.Get("Accept-Encoding")
-	if !strings.Contains(acceptEncoding, "br") {
-		// Fallback to plain HTML if client doesn't support brotli
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprintf(w, htmlTemplate, "Brotli (fallback)", "Brotli", "none (client doesn't support br)")
-		return
+	codings := rt.codings
+	if rt.vary && !accepts(r, codings) {
+		// Fall back to no compression for clients that do not accept it.
+		codings = nil
+	}
+	if len(codings) > 0 {
+		w.Header().Set("Content-Encoding", strings.Join(codings, ", "))
 	}
 
-	// Serve brotli-compressed response
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Header().Set("Content-Encoding", "br")
+	if rt.chunked {
+		streamChunks(w, body, codings)
+		return
+	}
+	encoded, err := encode([]byte(body), codings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(encoded)))
+	w.Write(encoded)
+}
 
-	br := brotli.NewWriter(w)
-	defer br.Close()
-	fmt.Fprintf(br, htmlTemplate, "Brotli", "Brotli", "br")
+// streamChunks sends body in four pieces 100ms apart, flushing the encoder
+// and the connection after each, so the proxy sees the compressed page
+// arrive in several chunks.
+func streamChunks(w http.ResponseWriter, body string, codings []string) {
+	flusher, _ := w.(http.Flusher)
+	enc, err := newEncoder(w, codings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer enc.Close()
+	piece := len(body)/4 + 1
+	for i := 0; i < len(body); i += piece {
+		enc.Write([]byte(body[i:min(i+piece, len(body))]))
+		enc.Flush()
+		if flusher != nil {
+			flusher.Flush()
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 }
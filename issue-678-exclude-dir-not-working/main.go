@@ -1,14 +1,31 @@
 package main
 
 import (
+	"embed"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, "Hello from issue-678 example")
 	})
-	fmt.Println("Server starting on :8080")
-	http.ListenAndServe(":8080", nil)
+	fmt.Println("Server starting on :" + port)
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
+		log.Fatal(err)
+	}
 }
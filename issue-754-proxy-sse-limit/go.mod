@@ -1,3 +1,7 @@
 module issue-754-proxy-sse-limit
 
 go 1.21
+
+require github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+
+replace github.com/air-verse/air-reproducible-example/reprokit => ../reprokit
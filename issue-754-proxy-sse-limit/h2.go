@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+)
+
+// The -h2 variant (.air.h2.toml) serves the app over TLS, where browsers
+// speak HTTP/2, with Air's proxy turned off: Air's proxy only speaks
+// plain HTTP/1.1. The app plays the proxy's part itself, adding to every
+// page a script that opens an EventSource on the same path as Air's
+// injected one. Over HTTP/2 all the streams of all the tabs share one
+// connection, so the per-host limit of six connections does not apply.
+
+// streamPath is the path of Air's injected reload stream.
+const streamPath = "/__air_internal/sse"
+
+// streamScript is what the -h2 variant adds before </body>.
+const streamScript = `<script>new EventSource("` + streamPath + `");</script>`
+
+// selfSigned returns a TLS config with a certificate for localhost made
+// at startup. Browsers ask to accept it once.
+func selfSigned() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// handleStream holds an event stream open like Air's reload stream, with
+// a comment every 15s so nothing in between closes it as idle.
+func handleStream(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+	flusher, _ := writer.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-ticker.C:
+			_, _ = writer.Write([]byte(": ping\n\n"))
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
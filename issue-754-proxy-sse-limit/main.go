@@ -1,28 +1,60 @@
 package main
 
 import (
+	"embed"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"sync/atomic"
-	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 var requestCount uint64
 
+// proxyPort is where Air's proxy listens, for the instructions on the page.
+var proxyPort = "8081"
+
+// pageScript is added to every page: the reload stream in the -h2 variant.
+var pageScript = ""
+
 func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	if p := os.Getenv("PROXY_PORT"); p != "" {
+		proxyPort = p
+	}
+	h2 := flag.Bool("h2", false, "serve over TLS and HTTP/2, with the reload stream served by the app")
+	flag.Parse()
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handleIndex)
 	mux.HandleFunc("/health", handleHealth)
 
-	server := &http.Server{
-		Addr:              ":8080",
-		Handler:           mux,
-		ReadHeaderTimeout: 5 * time.Second,
+	server := &reprokit.Server{Addr: ":" + port, Handler: mux}
+	scheme := "http"
+	if *h2 {
+		config, err := selfSigned()
+		if err != nil {
+			log.Fatalf("certificate: %v", err)
+		}
+		server.TLSConfig = config
+		mux.HandleFunc(streamPath, handleStream)
+		pageScript = streamScript
+		scheme = "https"
 	}
 
-	log.Println("listening on http://localhost:8080")
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	log.Printf("listening on %s://localhost:%s", scheme, port)
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
 }
@@ -40,7 +72,7 @@ func handleIndex(writer http.ResponseWriter, request *http.Request) {
   <h1>Air proxy SSE stream limit</h1>
   <p>Request count: %d</p>
   <ol>
-    <li>Open this page through Air proxy: <code>http://localhost:8081</code></li>
+    <li>Open this page through Air proxy: <code>http://localhost:%s</code></li>
     <li>Open 7 tabs (the button below helps)</li>
     <li>Notice the 7th tab hangs ~1 minute before loading</li>
   </ol>
@@ -51,8 +83,8 @@ func handleIndex(writer http.ResponseWriter, request *http.Request) {
     Each tab creates an injected EventSource connection via Air's proxy.
     Most browsers limit EventSource to 6 concurrent connections per host.
   </p>
-</body>
-</html>`, count)
+%s</body>
+</html>`, count, proxyPort, pageScript)
 }
 
 func handleHealth(writer http.ResponseWriter, request *http.Request) {
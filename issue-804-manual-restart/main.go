@@ -1,12 +1,26 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
 	fmt.Println("🚀 Starting server...")
 
 	// Simulate connecting to multiple data sources (slow startup)
@@ -15,13 +29,13 @@ func main() {
 	// 	time.Sleep(1 * time.Second)
 	// }
 
-	fmt.Println("✅ Server ready on http://localhost:8080")
+	fmt.Printf("✅ Server ready on http://localhost:%s\n", port)
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "Hello at %s\n", time.Now().Format(time.RFC3339))
 	})
 
-	if err := http.ListenAndServe(":8080", nil); err != nil {
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
 		fmt.Printf("Server error: %v\n", err)
 	}
 }
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
+)
+
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
+func main() {
+	version := "v1"
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Hello from %s\n", version)
+	})
+	fmt.Println("Server starting on :" + port)
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
+		fmt.Printf("Server error: %v\n", err)
+	}
+}
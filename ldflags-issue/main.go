@@ -1,17 +1,30 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 var Version = "unknown"
 var BuildTime = "unknown"
 
 func main() {
 	startTime := time.Now()
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
 
 	http.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
 		fmt.Fprintf(w, "Version: %s\n", Version)
@@ -19,6 +32,8 @@ func main() {
 		fmt.Fprintf(w, "Started: %s\n", startTime.Format(time.RFC3339))
 	})
 
-	log.Printf("starting server on :8080 (version=%s build_time=%s)", Version, BuildTime)
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Printf("starting server on :%s (version=%s build_time=%s)", port, Version, BuildTime)
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -1,16 +1,23 @@
 package main
 
 import (
+	"embed"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go static/*.html
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 var (
 	// comment
 	processStartTime = time.Now()
@@ -48,52 +55,42 @@ func main() {
 		fmt.Printf("[%s] No startup delay (STARTUP_DELAY=%s)\n", timestamp(), delayStr)
 	}
 
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	proxyPort := os.Getenv("PROXY_PORT")
+	if proxyPort == "" {
+		proxyPort = "8081"
+	}
+
 	// 3. Setup HTTP handlers
 	http.HandleFunc("/", serveIndex)
 	http.HandleFunc("/health", healthHandler)
 	http.HandleFunc("/api/time", timeHandler)
 
-	// 4. Start server in a goroutine to log when ready
-	fmt.Printf("[%s] Starting HTTP server on :8080...\n", timestamp())
-
-	server := &http.Server{Addr: ":8080"}
-
-	// Channel to signal server is listening
-	serverReady := make(chan struct{})
-
-	go func() {
-		// Small delay to ensure ListenAndServe has started
-		time.Sleep(10 * time.Millisecond)
-
-		// Try to connect to ourselves to verify server is ready
-		for i := 0; i < 50; i++ {
-			resp, err := http.Get("http://localhost:8080/health")
-			if err == nil {
-				resp.Body.Close()
-				serverReadyTime = time.Now()
-				close(serverReady)
-				return
-			}
-			time.Sleep(10 * time.Millisecond)
-		}
-		// Fallback - assume ready if we can't verify
-		serverReadyTime = time.Now()
-		close(serverReady)
-	}()
-
-	// Start server
-	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
-		}
-	}()
-
-	// Wait for server to be ready
-	<-serverReady
+	// 4. Start server; reprokit reports when it accepts connections
+	fmt.Printf("[%s] Starting HTTP server on :%s...\n", timestamp(), port)
+
+	server := &reprokit.Server{
+		Addr: ":" + port,
+		OnReady: func(net.Addr) {
+			serverReadyTime = time.Now()
+			printReady(port, proxyPort)
+		},
+	}
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+
+	fmt.Printf("\n[%s] Shutting down gracefully...\n", timestamp())
+}
+
+func printReady(port, proxyPort string) {
 	elapsed := serverReadyTime.Sub(processStartTime)
 	fmt.Printf("[%s] ========================================\n", timestamp())
 	fmt.Printf("[%s] ✓ Server ready to accept connections!\n", timestamp())
-	fmt.Printf("[%s] ✓ Listening on http://localhost:8080\n", timestamp())
+	fmt.Printf("[%s] ✓ Listening on http://localhost:%s\n", timestamp(), port)
 	fmt.Printf("[%s] ✓ Time from process start to ready: %v\n", timestamp(), elapsed)
 	fmt.Printf("[%s] ========================================\n", timestamp())
 	fmt.Printf("[%s] \n", timestamp())
@@ -106,16 +103,9 @@ func main() {
 		fmt.Printf("[%s] ✓ RESULT: Browser reload might succeed (but could race)\n", timestamp())
 	}
 	fmt.Printf("[%s] \n", timestamp())
-	fmt.Printf("[%s] Access the app through Air's proxy at: http://localhost:8081\n", timestamp())
+	fmt.Printf("[%s] Access the app through Air's proxy at: http://localhost:%s\n", timestamp(), proxyPort)
 	fmt.Printf("[%s] Press Ctrl+C to stop\n", timestamp())
 	fmt.Printf("[%s] ========================================\n", timestamp())
-
-	// Graceful shutdown
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-	<-sigCh
-
-	fmt.Printf("\n[%s] Shutting down gracefully...\n", timestamp())
 }
 
 func serveIndex(w http.ResponseWriter, r *http.Request) {
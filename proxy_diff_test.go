@@ -0,0 +1,235 @@
+package repro
+
+import (
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/internal/catalog"
+	"github.com/air-verse/air-reproducible-example/internal/refproxy"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+)
+
+// browser is the Accept-Encoding of a current browser.
+var browser = map[string]string{"Accept-Encoding": "gzip, deflate, br, zstd"}
+
+// proxyExample is what TestProxyDiff sends to one example: probes compared
+// while the app runs, and a source file changed to see that a request made
+// right after Air restarts the app is answered once it is back.
+type proxyExample struct {
+	probes  []refproxy.Probe
+	restart string
+}
+
+// proxyExamples covers every example with a proxy port in its meta.toml.
+var proxyExamples = map[string]proxyExample{
+	"issue-667-brotli-proxy": {
+		probes: []refproxy.Probe{
+			{Path: "/"},
+			{Path: "/plain"},
+			{Path: "/gzip", Header: browser},
+			{Path: "/deflate", Header: browser},
+			{Path: "/brotli", Header: browser},
+			{Path: "/zstd", Header: browser},
+			{Path: "/double", Header: browser},
+			{Path: "/no-vary", Header: browser},
+			// Gzip without Vary goes to clients that did not ask for it.
+			{Path: "/no-vary?without-accept-encoding"},
+			{Path: "/chunked", Header: browser},
+			{Path: "/data.json", Header: browser},
+		},
+		restart: "main.go",
+	},
+	"issue-754-proxy-sse-limit": {
+		probes: []refproxy.Probe{
+			{Path: "/", Mask: `Request count: \d+`},
+			{Path: "/health"},
+		},
+		restart: "main.go",
+	},
+	"proxy-reload-timing-issue-656": {
+		probes:  []refproxy.Probe{{Path: "/"}},
+		restart: "main.go",
+	},
+	"sse-chunking-issue": {
+		probes: []refproxy.Probe{
+			{Path: "/"},
+			{Path: "/ping"},
+			// Events come every 3s; two of them take 6s when nothing
+			// holds them back.
+			{Path: "/sse", Events: 2, Within: 8 * time.Second},
+		},
+		restart: "server.go",
+	},
+	// The socket itself is TestWebSocket's.
+	"websocket-proxy": {
+		probes:  []refproxy.Probe{{Path: "/"}},
+		restart: "main.go",
+	},
+}
+
+// TestProxyDiff puts every proxy example behind Air's proxy and behind the
+// reference proxy in internal/refproxy, sends the same requests to the app
+// and to both proxies, and compares what comes back. The reference proxy
+// must match the app, with its script injected once into every HTML page;
+// where Air's proxy does not, the difference is logged rather than failed,
+// like a BUG verdict in TestCollection. Needs $AIR_BIN.
+func TestProxyDiff(t *testing.T) {
+	bin := os.Getenv("AIR_BIN")
+	if bin == "" {
+		t.Skip("set AIR_BIN to an air binary (or \"air\") to compare Air's proxy with the reference proxy")
+	}
+	examples, err := catalog.Discover(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range examples {
+		if _, ok := e.Ports["proxy"]; !ok {
+			continue
+		}
+		ex, ok := proxyExamples[e.Name()]
+		if !ok {
+			t.Errorf("%s has a proxy port but no entry in proxyExamples", e.Name())
+			continue
+		}
+		t.Run(e.Name(), func(t *testing.T) {
+			sc, err := scenario.LoadExample(e.Dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := scenario.ExecuteFunc(sc, bin, func(run *scenario.Scenario, s *runner.Session) error {
+				if run.Ready != "" {
+					if err := s.WaitLog(run.Ready, 1, 2*time.Minute); err != nil {
+						return err
+					}
+				}
+				ref := httptest.NewServer(refproxy.New(run.Ports["app"]))
+				defer ref.Close()
+				p := proxyDiff{
+					t:      t,
+					direct: "http://localhost:" + strconv.Itoa(run.Ports["app"]),
+					air:    "http://localhost:" + strconv.Itoa(run.Ports["proxy"]),
+					ref:    ref.URL,
+				}
+				for _, probe := range ex.probes {
+					p.compare(probe)
+				}
+				if ex.restart != "" {
+					n := s.Count("running...")
+					// A real change: these configs skip unchanged files.
+					if err := s.AppendFile(ex.restart, "\n", ""); err != nil {
+						return err
+					}
+					if err := s.WaitLog("running...", n+1, time.Minute); err != nil {
+						return err
+					}
+					p.afterRestart(ex.probes[0])
+				}
+				return nil
+			})
+			if res.Verdict == runner.Error {
+				t.Fatalf("%s\nair log:\n%s", res.Reason, res.AirLog)
+			}
+		})
+	}
+}
+
+// proxyDiff sends probes to the app and both proxies.
+type proxyDiff struct {
+	t                *testing.T
+	direct, air, ref string
+}
+
+// fetchAll sends probe to the app, Air's proxy and the reference proxy at
+// the same time, so event streams are read over the same window.
+func (p proxyDiff) fetchAll(probe refproxy.Probe) (direct, air, ref *refproxy.Response, errs [3]error) {
+	type result struct {
+		r   *refproxy.Response
+		err error
+	}
+	var out [3]chan result
+	for i, base := range []string{p.direct, p.air, p.ref} {
+		out[i] = make(chan result, 1)
+		go func() {
+			r, err := refproxy.Fetch(base, probe)
+			out[i] <- result{r, err}
+		}()
+	}
+	var rs [3]*refproxy.Response
+	for i := range out {
+		got := <-out[i]
+		rs[i], errs[i] = got.r, got.err
+	}
+	return rs[0], rs[1], rs[2], errs
+}
+
+func (p proxyDiff) compare(probe refproxy.Probe) {
+	t := p.t
+	direct, air, ref, errs := p.fetchAll(probe)
+	if errs[0] != nil {
+		t.Errorf("%s: app: %v", probe.Path, errs[0])
+		return
+	}
+	// Every page gets one reload script, anything else none.
+	scripts := 0
+	if refproxy.IsHTML(direct.Header) {
+		scripts = 1
+	}
+	whole := probe.Events == 0
+
+	switch {
+	case errs[2] != nil:
+		t.Errorf("%s: reference proxy: %v", probe.Path, errs[2])
+	default:
+		for _, d := range refproxy.Diff(direct, ref) {
+			t.Errorf("%s: reference proxy: %s", probe.Path, d)
+		}
+		if whole {
+			for _, d := range refproxy.Consistency(ref) {
+				t.Errorf("%s: reference proxy: %s", probe.Path, d)
+			}
+		}
+		if ref.Scripts != scripts {
+			t.Errorf("%s: reference proxy injected %d scripts, want %d", probe.Path, ref.Scripts, scripts)
+		}
+	}
+
+	switch {
+	case errs[1] != nil:
+		t.Logf("%s: Air's proxy: %v", probe.Path, errs[1])
+	default:
+		for _, d := range refproxy.Diff(direct, air) {
+			t.Logf("%s: Air's proxy: %s", probe.Path, d)
+		}
+		if whole {
+			for _, d := range refproxy.Consistency(air) {
+				t.Logf("%s: Air's proxy: %s", probe.Path, d)
+			}
+		}
+		if air.Scripts != scripts {
+			t.Logf("%s: Air's proxy injected %d scripts, want %d", probe.Path, air.Scripts, scripts)
+		}
+	}
+}
+
+// afterRestart sends probe to both proxies right after Air started the
+// new app process, which may not accept connections yet.
+func (p proxyDiff) afterRestart(probe refproxy.Probe) {
+	t := p.t
+	_, air, ref, errs := p.fetchAll(probe)
+	switch {
+	case errs[2] != nil:
+		t.Errorf("%s after restart: reference proxy: %v", probe.Path, errs[2])
+	case ref.Status != 200:
+		t.Errorf("%s after restart: reference proxy: status %d", probe.Path, ref.Status)
+	}
+	switch {
+	case errs[1] != nil:
+		t.Logf("%s after restart: Air's proxy: %v", probe.Path, errs[1])
+	case air.Status != 200:
+		t.Logf("%s after restart: Air's proxy: status %d", probe.Path, air.Status)
+	}
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
+	"github.com/air-verse/air-reproducible-example/reprokit/provenance"
 )
 
-// BuildTime will be injected at compile time via -ldflags
-// This is the REAL compile time, not the startup time
-var BuildTime string
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
 
 func main() {
+	// BuildTime is when the binary was linked, the REAL compile time, not
+	// the startup time
+	BuildTime := provenance.Current().Built.Format("15:04:05.000")
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
 	fmt.Printf("========================================\n")
 	fmt.Printf("🚀 Server started\n")
 	fmt.Printf("📅 BUILD TIME: %s\n", BuildTime)
@@ -27,7 +41,9 @@ func main() {
 		fmt.Fprintf(w, "Hello! Build Time: %s, Helper: %s\n", BuildTime, getVersion())
 	})
 
-	log.Println("Server listening on :8080")
-	log.Printf("Try: curl http://localhost:8080/version")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Println("Server listening on :" + port)
+	log.Printf("Try: curl http://localhost:%s/version", port)
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,3 @@
+module github.com/air-verse/air-reproducible-example/reprokit
+
+go 1.21
@@ -0,0 +1,183 @@
+// Package provenance tells which sources the running binary was built from.
+//
+// An example embeds the files Air watches for it and registers them with
+// reprokit.Sources, which calls Embed:
+//
+//	//go:embed *.go myfile.txt
+//	var sources embed.FS
+//
+//	func init() { reprokit.Sources(sources) }
+//
+// The embedded copy is read by the same go build that compiles the package,
+// so its hash identifies exactly the revision in the binary, however many
+// edits happened while air was building. reprokit serves Current as part of
+// /__repro/info.
+package provenance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Build describes the running binary.
+type Build struct {
+	// ID is the Go build ID of the executable.
+	ID string `json:"id"`
+	// Built is when the executable was written.
+	Built time.Time `json:"built"`
+	// Run counts the starts of this executable path, this one included;
+	// under air it grows by one per restart.
+	Run int `json:"run"`
+	// Package is the main package directory relative to the module root.
+	Package string `json:"package"`
+	// Files are the embedded sources, relative to Package.
+	Files []string `json:"files,omitempty"`
+	// Patterns match the files on disk of the same kinds as Files; the
+	// runner hashes those too, so a new file counts as a change.
+	Patterns []string `json:"patterns,omitempty"`
+	// SourceHash is Hash of Files as they were at build time.
+	SourceHash string `json:"source_hash,omitempty"`
+}
+
+var (
+	sources fs.FS
+	once    sync.Once
+	current Build
+)
+
+// Embed registers the sources compiled into the binary. Call it from init.
+func Embed(fsys fs.FS) {
+	sources = fsys
+}
+
+// Current describes the running binary. The first call bumps the run
+// counter, so it is made once at startup by reprokit.
+func Current() Build {
+	once.Do(func() { current = describe(sources) })
+	return current
+}
+
+func describe(fsys fs.FS) Build {
+	var b Build
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		b.Package = strings.TrimPrefix(strings.TrimPrefix(bi.Path, bi.Main.Path), "/")
+	}
+	if exe, err := os.Executable(); err == nil {
+		b.ID = buildID(exe)
+		if fi, err := os.Stat(exe); err == nil {
+			b.Built = fi.ModTime()
+		}
+		b.Run = bumpRuns(exe + ".runs")
+	}
+	if fsys != nil {
+		fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				b.Files = append(b.Files, path)
+			}
+			return nil
+		})
+		b.Patterns = patterns(b.Files)
+		b.SourceHash, _ = Hash(fsys, b.Files)
+	}
+	return b
+}
+
+// patterns turns each file into a glob for its directory and extension:
+// lib/greet/greet.go gives lib/greet/*.go. A file without an extension,
+// such as Makefile, stands for itself.
+func patterns(files []string) []string {
+	var out []string
+	for _, f := range files {
+		p := f
+		if ext := path.Ext(f); ext != "" {
+			p = path.Join(path.Dir(f), "*"+ext)
+		}
+		if !slices.Contains(out, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Hash digests files read from fsys: sha256 over, per file in name order,
+// the name, a NUL, the length, a NUL and the content. The runner hashes the
+// files on disk the same way to tell a stale binary from a fresh one.
+func Hash(fsys fs.FS, files []string) (string, error) {
+	names := append([]string(nil), files...)
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// The linker writes the build ID into every Go executable between these.
+var (
+	buildIDPrefix = []byte("\xff Go build ID: \"")
+	buildIDSuffix = []byte("\"\n \xff")
+)
+
+func buildID(exe string) string {
+	// On ELF the ID only lives in a note: namesz, descsz, type, "Go\0\0",
+	// then the ID.
+	if f, err := elf.Open(exe); err == nil {
+		defer f.Close()
+		if s := f.Section(".note.go.buildid"); s != nil {
+			note, err := s.Data()
+			if err != nil || len(note) < 16 {
+				return ""
+			}
+			n := int(f.ByteOrder.Uint32(note[4:]))
+			if len(note) < 16+n {
+				return ""
+			}
+			return string(note[16 : 16+n])
+		}
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		return ""
+	}
+	// The prefix also appears in this package's own string constants;
+	// only the linker's copy is followed by the suffix.
+	for {
+		i := bytes.Index(data, buildIDPrefix)
+		if i < 0 {
+			return ""
+		}
+		data = data[i+len(buildIDPrefix):]
+		j := bytes.IndexByte(data, '"')
+		if j >= 0 && bytes.HasPrefix(data[j:], buildIDSuffix) {
+			return string(data[:j])
+		}
+	}
+}
+
+// bumpRuns increments the counter kept next to the executable. Air writes
+// each build to the same path, so the counter survives rebuilds.
+func bumpRuns(path string) int {
+	data, _ := os.ReadFile(path)
+	n, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	n++
+	os.WriteFile(path, []byte(strconv.Itoa(n)+"\n"), 0o644)
+	return n
+}
@@ -0,0 +1,194 @@
+// Package reprokit starts the HTTP server of an example the same way in every
+// reproduction, so the runner can tell when an app is up without knowing its
+// log text.
+//
+// A server started with ListenAndServe
+//
+//   - prints "READY pid=<pid> addr=<listen address>" once it accepts
+//     connections,
+//   - answers /health with "ok", unless the app's own ServeMux handles it,
+//     and /__repro/info with a JSON description of the running process and
+//     the build it runs (see Sources and package provenance),
+//   - shuts down gracefully on SIGINT or SIGTERM, printing
+//     "STOPPING pid=<pid> signal=<signal>" first.
+//
+// Examples use it through a replace directive:
+//
+//	require github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+//	replace github.com/air-verse/air-reproducible-example/reprokit => ../reprokit
+package reprokit
+
+import (
+	"context"
+	"crypto/tls"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit/provenance"
+)
+
+// ReadyPrefix starts the line printed once the server accepts connections.
+const ReadyPrefix = "READY pid="
+
+// InfoPath is the endpoint describing the running process.
+const InfoPath = "/__repro/info"
+
+var started = time.Now()
+
+// Server is an http.Server with the reprokit conventions.
+type Server struct {
+	// Addr is the listen address, e.g. ":8080".
+	Addr string
+	// Handler serves everything but /health and /__repro/info;
+	// http.DefaultServeMux when nil.
+	Handler http.Handler
+	// ShutdownTimeout bounds the graceful shutdown after a signal; 5s
+	// when zero.
+	ShutdownTimeout time.Duration
+	// OnReady, if set, runs right after the READY line.
+	OnReady func(addr net.Addr)
+	// OnShutdown, if set, runs after the STOPPING line and before the
+	// graceful shutdown, which does not wait for hijacked connections
+	// such as WebSockets. It should close them.
+	OnShutdown func()
+	// TLSConfig, if set, makes the server speak TLS with its certificates,
+	// and HTTP/2 with clients that offer it.
+	TLSConfig *tls.Config
+}
+
+// Info is the body of /__repro/info.
+type Info struct {
+	PID        int       `json:"pid"`
+	PPID       int       `json:"ppid"`
+	Addr       string    `json:"addr"`
+	Started    time.Time `json:"started"`
+	Uptime     float64   `json:"uptime_seconds"`
+	Executable string    `json:"executable"`
+	Args       []string  `json:"args"`
+	GoVersion  string    `json:"go_version"`
+	// Build is provenance.Current.
+	Build provenance.Build `json:"build"`
+}
+
+// ListenAndServe serves handler on addr until SIGINT or SIGTERM.
+func ListenAndServe(addr string, handler http.Handler) error {
+	s := &Server{Addr: addr, Handler: handler}
+	return s.ListenAndServe()
+}
+
+// Sources registers the files compiled into the binary, so /__repro/info
+// can tell which revision of them is running. Call it from init with the
+// files the example's .air.toml watches:
+//
+//	//go:embed *.go myfile.txt
+//	var sources embed.FS
+//
+//	func init() { reprokit.Sources(sources) }
+//
+// Embedded directories need their own patterns, e.g. lib/greet/*.go.
+func Sources(fsys embed.FS) {
+	provenance.Embed(fsys)
+}
+
+// ListenAndServe listens on s.Addr, prints the READY line and serves until
+// SIGINT or SIGTERM, then shuts down gracefully and returns nil.
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	// Bump the run counter before anything can ask for it.
+	provenance.Current()
+	h := s.Handler
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	mux := http.NewServeMux()
+	if !handles(h, "/health") {
+		mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, "ok")
+		})
+	}
+	mux.HandleFunc(InfoPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info(ln.Addr()))
+	})
+	mux.Handle("/", h)
+	srv := &http.Server{Handler: mux, TLSConfig: s.TLSConfig}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	served := make(chan error, 1)
+	go func() {
+		if s.TLSConfig != nil {
+			served <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		served <- srv.Serve(ln)
+	}()
+	fmt.Printf("%s%d addr=%s\n", ReadyPrefix, os.Getpid(), ln.Addr())
+	if s.OnReady != nil {
+		s.OnReady(ln.Addr())
+	}
+
+	select {
+	case err := <-served:
+		return err
+	case sig := <-sigs:
+		fmt.Printf("STOPPING pid=%d signal=%s\n", os.Getpid(), sig)
+	}
+	if s.OnShutdown != nil {
+		s.OnShutdown()
+	}
+	timeout := s.ShutdownTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err = srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Connections that outlive the timeout, like SSE streams, are cut.
+		return srv.Close()
+	}
+	return err
+}
+
+// handles reports whether h is a ServeMux with its own route for path. Some
+// examples serve a richer /health that their pages and scripts rely on.
+func handles(h http.Handler, path string) bool {
+	mux, ok := h.(*http.ServeMux)
+	if !ok {
+		return false
+	}
+	_, pattern := mux.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: path}})
+	return strings.HasSuffix(pattern, path)
+}
+
+func info(addr net.Addr) Info {
+	exe, _ := os.Executable()
+	return Info{
+		PID:        os.Getpid(),
+		PPID:       os.Getppid(),
+		Addr:       addr.String(),
+		Started:    started,
+		Uptime:     time.Since(started).Seconds(),
+		Executable: exe,
+		Args:       os.Args,
+		GoVersion:  runtime.Version(),
+		Build:      provenance.Current(),
+	}
+}
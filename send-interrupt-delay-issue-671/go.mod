@@ -1,3 +1,7 @@
 module send-interrupt-delay-issue-671
 
 go 1.21
+
+require github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+
+replace github.com/air-verse/air-reproducible-example/reprokit => ../reprokit
@@ -1,20 +1,26 @@
 package main
 
 import (
-	"context"
+	"embed"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
-	// Setup signal handler
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "9090"
+	}
 
 	// Create HTTP server
 	mux := http.NewServeMux()
@@ -24,29 +30,16 @@ func main() {
 		fmt.Fprintf(w, `{"status":"ok","message":"pong"}`)
 	})
 
-	server := &http.Server{
-		Addr:    ":9090",
-		Handler: mux,
-	}
-
-	// Start server in goroutine
-	go func() {
-		log.Println("Server started on :9090")
-		log.Println("Try: curl http://localhost:9090/ping")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
-		}
-	}()
-
-	// Wait for SIGINT
-	<-sigChan
-	log.Println("Received SIGINT, shutting down gracefully...")
-
 	// Graceful shutdown with 100ms timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
-	defer cancel()
+	server := &reprokit.Server{
+		Addr:            ":" + port,
+		Handler:         mux,
+		ShutdownTimeout: 100 * time.Millisecond,
+	}
 
-	if err := server.Shutdown(ctx); err != nil {
+	log.Println("Server started on :" + port)
+	log.Printf("Try: curl http://localhost:%s/ping", port)
+	if err := server.ListenAndServe(); err != nil {
 		log.Printf("Shutdown error: %v", err)
 	}
 
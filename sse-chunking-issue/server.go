@@ -1,17 +1,34 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
+	"github.com/air-verse/air-reproducible-example/reprokit"
 	"github.com/gin-gonic/gin"
 )
 
+//go:embed *.go static/*.html
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3002"
+	}
+	proxyPort := os.Getenv("PROXY_PORT")
+	if proxyPort == "" {
+		proxyPort = "3082"
+	}
+
 	r := gin.Default()
 
 	// Serve static files
@@ -19,14 +36,14 @@ func main() {
 
 	// Homepage - serves the HTML client
 	r.GET("/", func(c *gin.Context) {
-		c.File("./static/index.html")
-	})
-
-	// Health check endpoint
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "ok",
-		})
+		page, err := os.ReadFile("./static/index.html")
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		// The page hard-codes the default ports; point it at the ones in use.
+		html := strings.NewReplacer("3002", port, "3082", proxyPort).Replace(string(page))
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
 	})
 
 	// Ping endpoint
@@ -99,13 +116,12 @@ func main() {
 	})
 
 	// Start server
-	port := ":3002"
-	log.Printf("Starting server on port %s", port)
-	log.Printf("Access the demo at: http://localhost:3002/")
-	log.Printf("SSE endpoint: http://localhost:3002/sse")
-	log.Printf("With Air proxy: http://localhost:3082/")
+	log.Printf("Starting server on port :%s", port)
+	log.Printf("Access the demo at: http://localhost:%s/", port)
+	log.Printf("SSE endpoint: http://localhost:%s/sse", port)
+	log.Printf("With Air proxy: http://localhost:%s/", proxyPort)
 
-	if err := r.Run(port); err != nil {
+	if err := reprokit.ListenAndServe(":"+port, r); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
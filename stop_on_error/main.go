@@ -1,12 +1,20 @@
 package main
 
 import (
+	"embed"
 	"log"
 	"net/http"
+	"os"
 
+	"github.com/air-verse/air-reproducible-example/reprokit"
 	"github.com/gin-gonic/gin"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
 	// Create a Gin router with default middleware (logger and recovery)
 	r := gin.Default()
@@ -19,9 +27,12 @@ func main() {
 		})
 	})
 
-	// Start server on port 8080 (default)
-	// Server will listen on 0.0.0.0:8080 (localhost:8080 on Windows)
-	if err := r.Run(); err != nil {
+	// Start server on $PORT, 8080 by default
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	if err := reprokit.ListenAndServe(":"+port, r); err != nil {
 		log.Fatalf("failed to run server: %v", err)
 	}
 }
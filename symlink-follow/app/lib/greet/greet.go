@@ -0,0 +1,4 @@
+// Package greet is imported through the app/greet symlink.
+package greet
+
+func Message() string { return "greet v1" }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
+
+	"symlink-follow/app/external"
+	"symlink-follow/app/greet"
+)
+
+//go:embed *.go lib/greet/*.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	// Parsed once: a template change only shows after air restarts the app.
+	page := template.Must(template.ParseGlob("app/templates/*.html"))
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s\n%s\n", greet.Message(), external.Name())
+		page.ExecuteTemplate(w, "index.html", nil)
+	})
+	fmt.Println("Server starting on :" + port)
+	if err := reprokit.ListenAndServe(":"+port, nil); err != nil {
+		fmt.Printf("Server error: %v\n", err)
+	}
+}
@@ -0,0 +1,5 @@
+// Package external lives outside air's root and is reached through the
+// app/external symlink.
+package external
+
+func Name() string { return "external v1" }
@@ -0,0 +1,9 @@
+module websocket-proxy
+
+go 1.21
+
+require github.com/gorilla/websocket v1.5.3
+
+require github.com/air-verse/air-reproducible-example/reprokit v0.0.0
+
+replace github.com/air-verse/air-reproducible-example/reprokit => ../reprokit
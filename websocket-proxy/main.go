@@ -0,0 +1,203 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
+	"github.com/gorilla/websocket"
+)
+
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
+// subprotocol is the one /ws speaks; a client asking for it must get it
+// back in the handshake.
+const subprotocol = "echo"
+
+// /ws echoes every text and binary message with its type and answers two
+// commands, so a client can check every kind of frame in both directions:
+//
+//	ping <payload>        the server pings with payload, and once the pong
+//	                      comes back sends the text "pong <payload>"
+//	close <code> <reason> the server closes with that code and reason
+//
+// The server greets with "welcome", returns a close from the client with
+// the same code and reason, and closes with 1001 (going away) when Air
+// stops the app.
+var upgrader = websocket.Upgrader{
+	Subprotocols: []string{subprotocol},
+	// The page is opened through Air's proxy as well as directly.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// conns are the open connections, closed on shutdown.
+var conns = struct {
+	sync.Mutex
+	m  map[*websocket.Conn]bool
+	wg sync.WaitGroup
+}{m: map[*websocket.Conn]bool{}}
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3004"
+	}
+	proxyPort := os.Getenv("PROXY_PORT")
+	if proxyPort == "" {
+		proxyPort = "3084"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleIndex)
+	mux.HandleFunc("/ws", handleWS)
+
+	log.Printf("listening on http://localhost:%s", port)
+	log.Printf("through Air's proxy: http://localhost:%s", proxyPort)
+	server := &reprokit.Server{Addr: ":" + port, Handler: mux, OnShutdown: closeAll}
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("server error: %v", err)
+	}
+}
+
+func handleWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has answered with an error status.
+		log.Printf("upgrade: %v", err)
+		return
+	}
+	conns.Lock()
+	conns.m[conn] = true
+	conns.wg.Add(1)
+	conns.Unlock()
+	defer func() {
+		conns.Lock()
+		delete(conns.m, conn)
+		conns.Unlock()
+		conn.Close()
+		conns.wg.Done()
+	}()
+
+	conn.SetCloseHandler(func(code int, text string) error {
+		// Return the client's code and reason rather than gorilla's
+		// default, which drops the reason.
+		msg := websocket.FormatCloseMessage(code, text)
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		return nil
+	})
+	conn.SetPongHandler(func(payload string) error {
+		return conn.WriteMessage(websocket.TextMessage, []byte("pong "+payload))
+	})
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("welcome")); err != nil {
+		return
+	}
+	for {
+		kind, msg, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("read: %v", err)
+			}
+			return
+		}
+		if kind == websocket.TextMessage {
+			cmd, arg, _ := strings.Cut(string(msg), " ")
+			switch cmd {
+			case "ping":
+				err = conn.WriteControl(websocket.PingMessage, []byte(arg), time.Now().Add(time.Second))
+				if err != nil {
+					return
+				}
+				continue
+			case "close":
+				codeText, reason, _ := strings.Cut(arg, " ")
+				code, err := strconv.Atoi(codeText)
+				if err != nil {
+					code = websocket.CloseNormalClosure
+				}
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+				// Wait for the client's close, which ends ReadMessage.
+				continue
+			}
+		}
+		if err := conn.WriteMessage(kind, msg); err != nil {
+			return
+		}
+	}
+}
+
+// closeAll closes every connection with 1001 and waits up to a second for
+// the clients to answer, so they see a clean close rather than a dropped
+// connection when Air restarts the app.
+func closeAll() {
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server restarting")
+	conns.Lock()
+	for conn := range conns.m {
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	}
+	conns.Unlock()
+	done := make(chan struct{})
+	go func() {
+		conns.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+	}
+}
+
+func handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, `<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>WebSocket through Air's proxy</title>
+</head>
+<body>
+  <h1>WebSocket echo</h1>
+  <p>Open this page directly and through Air's proxy. Messages come back as
+  they were sent; "Ping" asks the server to ping this page, whose pong makes
+  it answer "pong hello". Saving main.go should close the socket with 1001.</p>
+  <p>
+    <input id="msg" value="hello" />
+    <button id="send">Send</button>
+    <button id="ping">Ping</button>
+    <button id="close">Close with 4000</button>
+  </p>
+  <pre id="log"></pre>
+  <script>
+    const log = (line) => {
+      document.getElementById("log").textContent += line + "\n";
+    };
+    const scheme = location.protocol === "https:" ? "wss://" : "ws://";
+    const ws = new WebSocket(scheme + location.host + "/ws", "echo");
+    ws.onopen = () => log("open, subprotocol " + JSON.stringify(ws.protocol));
+    ws.onmessage = (event) => log("< " + event.data);
+    ws.onclose = (event) => log("closed: " + event.code + " " + JSON.stringify(event.reason) + (event.wasClean ? "" : " (not clean)"));
+    ws.onerror = () => log("error");
+    document.getElementById("send").onclick = () => {
+      const text = document.getElementById("msg").value;
+      log("> " + text);
+      ws.send(text);
+    };
+    document.getElementById("ping").onclick = () => ws.send("ping hello");
+    document.getElementById("close").onclick = () => ws.close(4000, "bye");
+  </script>
+</body>
+</html>`)
+}
@@ -0,0 +1,247 @@
+package repro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/air-verse/air-reproducible-example/internal/refproxy"
+	"github.com/air-verse/air-reproducible-example/internal/runner"
+	"github.com/air-verse/air-reproducible-example/internal/scenario"
+)
+
+// wsTarget is one way to reach websocket-proxy's /ws. report is t.Errorf
+// where the socket must work and t.Logf for Air's proxy.
+type wsTarget struct {
+	name   string
+	url    string
+	report func(format string, args ...any)
+}
+
+// TestWebSocket runs websocket-proxy under Air and checks its echo socket
+// directly, through the reference proxy and through Air's proxy: the
+// upgrade and its subprotocol, text and binary frames both ways, pings
+// from either side answered with pongs, and close codes and reasons from
+// either side. It then keeps a socket open on each while Air restarts the
+// app, which must close them with 1001. What goes wrong through Air's
+// proxy is logged, like in TestProxyDiff. Needs $AIR_BIN.
+func TestWebSocket(t *testing.T) {
+	bin := os.Getenv("AIR_BIN")
+	if bin == "" {
+		t.Skip("set AIR_BIN to an air binary (or \"air\") to run websocket-proxy")
+	}
+	sc, err := scenario.LoadExample(filepath.Join(".", "websocket-proxy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := scenario.ExecuteFunc(sc, bin, func(run *scenario.Scenario, s *runner.Session) error {
+		if run.Ready != "" {
+			if err := s.WaitLog(run.Ready, 1, 2*time.Minute); err != nil {
+				return err
+			}
+		}
+		ref := httptest.NewServer(refproxy.New(run.Ports["app"]))
+		defer ref.Close()
+		targets := []wsTarget{
+			{"app", "ws://localhost:" + strconv.Itoa(run.Ports["app"]) + "/ws", t.Errorf},
+			{"reference proxy", "ws://" + ref.Listener.Addr().String() + "/ws", t.Errorf},
+			{"Air's proxy", "ws://localhost:" + strconv.Itoa(run.Ports["proxy"]) + "/ws", t.Logf},
+		}
+		for _, tg := range targets {
+			if err := checkEcho(tg.url); err != nil {
+				tg.report("%s: %v", tg.name, err)
+			}
+		}
+
+		// Read every open socket while Air restarts the app, so each
+		// answers the app's close as a browser would.
+		closed := make([]chan error, len(targets))
+		for i, tg := range targets {
+			c, err := dialEcho(tg.url)
+			if err != nil {
+				tg.report("%s: before restart: %v", tg.name, err)
+				continue
+			}
+			defer c.Close()
+			closed[i] = make(chan error, 1)
+			go func() {
+				closed[i] <- expectClose(c, websocket.CloseGoingAway, "server restarting", time.Minute)
+			}()
+		}
+		n := s.Count("running...")
+		// A real change: Air skips rebuilding on a bare touch.
+		if err := s.AppendFile("main.go", "\n", ""); err != nil {
+			return err
+		}
+		if err := s.WaitLog("running...", n+1, time.Minute); err != nil {
+			return err
+		}
+		for i, tg := range targets {
+			if closed[i] == nil {
+				continue
+			}
+			if err := <-closed[i]; err != nil {
+				tg.report("%s: on restart: %v", tg.name, err)
+			}
+		}
+		return nil
+	})
+	if res.Verdict == runner.Error {
+		t.Fatalf("%s\nair log:\n%s", res.Reason, res.AirLog)
+	}
+}
+
+// dialEcho opens the socket asking for the echo subprotocol and reads the
+// greeting.
+func dialEcho(url string) (*websocket.Conn, error) {
+	d := websocket.Dialer{Subprotocols: []string{"echo"}, HandshakeTimeout: 10 * time.Second}
+	c, resp, err := d.Dial(url, nil)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("upgrade: %v (status %d)", err, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("upgrade: %v", err)
+	}
+	if got := c.Subprotocol(); got != "echo" {
+		c.Close()
+		return nil, fmt.Errorf("upgrade: subprotocol %q, want \"echo\"", got)
+	}
+	if err := expect(c, websocket.TextMessage, "welcome"); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkEcho goes through every kind of frame on one socket, then closes
+// another from the client's side.
+func checkEcho(url string) error {
+	c, err := dialEcho(url)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+		return err
+	}
+	if err := expect(c, websocket.TextMessage, "hello"); err != nil {
+		return err
+	}
+	binary := string([]byte{0, 1, 2, 0xfe, 0xff})
+	if err := c.WriteMessage(websocket.BinaryMessage, []byte(binary)); err != nil {
+		return err
+	}
+	if err := expect(c, websocket.BinaryMessage, binary); err != nil {
+		return err
+	}
+
+	// The client pings; the pong comes back before the echo of what was
+	// sent after the ping.
+	pongs := make(chan string, 1)
+	c.SetPongHandler(func(payload string) error {
+		pongs <- payload
+		return nil
+	})
+	if err := c.WriteControl(websocket.PingMessage, []byte("from client"), time.Now().Add(time.Second)); err != nil {
+		return err
+	}
+	if err := c.WriteMessage(websocket.TextMessage, []byte("after ping")); err != nil {
+		return err
+	}
+	if err := expect(c, websocket.TextMessage, "after ping"); err != nil {
+		return err
+	}
+	select {
+	case p := <-pongs:
+		if p != "from client" {
+			return fmt.Errorf("pong %q, want \"from client\"", p)
+		}
+	default:
+		return errors.New("no pong for the client's ping")
+	}
+
+	// The server pings; the client's pong makes it send "pong <payload>".
+	pings := make(chan string, 1)
+	c.SetPingHandler(func(payload string) error {
+		pings <- payload
+		return c.WriteControl(websocket.PongMessage, []byte(payload), time.Now().Add(time.Second))
+	})
+	if err := c.WriteMessage(websocket.TextMessage, []byte("ping from server")); err != nil {
+		return err
+	}
+	if err := expect(c, websocket.TextMessage, "pong from server"); err != nil {
+		return err
+	}
+	select {
+	case p := <-pings:
+		if p != "from server" {
+			return fmt.Errorf("ping %q, want \"from server\"", p)
+		}
+	default:
+		return errors.New("the server's ping never arrived")
+	}
+
+	// The server closes with a code and reason of the client's choice.
+	if err := c.WriteMessage(websocket.TextMessage, []byte("close 4001 server bye")); err != nil {
+		return err
+	}
+	if err := expectClose(c, 4001, "server bye", 5*time.Second); err != nil {
+		return fmt.Errorf("server close: %w", err)
+	}
+
+	// The client closes; the server answers with the same code and reason.
+	c2, err := dialEcho(url)
+	if err != nil {
+		return err
+	}
+	defer c2.Close()
+	msg := websocket.FormatCloseMessage(4002, "client bye")
+	if err := c2.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); err != nil {
+		return err
+	}
+	if err := expectClose(c2, 4002, "client bye", 5*time.Second); err != nil {
+		return fmt.Errorf("client close: %w", err)
+	}
+	return nil
+}
+
+// expect reads the next message and checks its type and data.
+func expect(c *websocket.Conn, kind int, want string) error {
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got, data, err := c.ReadMessage()
+	if err != nil {
+		return fmt.Errorf("waiting for %q: %w", want, err)
+	}
+	if got != kind || !bytes.Equal(data, []byte(want)) {
+		return fmt.Errorf("got message type %d %q, want type %d %q", got, data, kind, want)
+	}
+	return nil
+}
+
+// expectClose reads until a close frame with code and text arrives, and
+// fails on anything else, including a connection dropped without one.
+func expectClose(c *websocket.Conn, code int, text string, within time.Duration) error {
+	c.SetReadDeadline(time.Now().Add(within))
+	_, data, err := c.ReadMessage()
+	var ce *websocket.CloseError
+	switch {
+	case errors.As(err, &ce):
+		if ce.Code != code || ce.Text != text {
+			return fmt.Errorf("closed with %d %q, want %d %q", ce.Code, ce.Text, code, text)
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("no close frame: %w", err)
+	default:
+		return fmt.Errorf("got %q, want a close with %d %q", data, code, text)
+	}
+}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+
+	"github.com/air-verse/air-reproducible-example/reprokit"
 )
 
+//go:embed *.go
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
 	pid := os.Getpid()
 	ppid := os.Getppid()
@@ -18,19 +24,12 @@ func main() {
 		fmt.Fprintf(w, "Hello! PID=%d, PPID=%d\n", pid, ppid)
 	})
 
-	// Graceful shutdown signal handling
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		sig := <-sigCh
-		fmt.Printf("Received signal: %v, shutting down...\n", sig)
-		os.Exit(0)
-	}()
-
 	addr := ":8080"
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
 	fmt.Printf("Listening on %s\n", addr)
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	if err := reprokit.ListenAndServe(addr, nil); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -1,18 +1,25 @@
 package main
 
 import (
-	"context"
+	"embed"
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
+	"github.com/air-verse/air-reproducible-example/reprokit"
 	"github.com/gin-gonic/gin"
 )
 
+//go:embed *.go templates/*.tmpl
+var sources embed.FS
+
+func init() { reprokit.Sources(sources) }
+
 func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
 
 	r := gin.Default()
 	r.GET("/ping", func(c *gin.Context) {
@@ -28,37 +35,9 @@ func main() {
 			"title": "Main website",
 		})
 	})
-	srv := &http.Server{
-		Addr:    ":8080",
-		Handler: r.Handler(),
-	}
-
-	go func() {
-		// service connections
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s\n", err)
-		}
-	}()
-
-	// Wait for interrupt signal to gracefully shutdown the server with
-	// a timeout of 5 seconds.
-	quit := make(chan os.Signal, 1)
-	// kill (no param) default send syscall.SIGTERM
-	// kill -2 is syscall.SIGINT
-	// kill -9 is syscall. SIGKILL but can"t be catch, so don't need add it
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutdown Server ...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
+	// Shuts down gracefully on SIGINT or SIGTERM with a timeout of 5 seconds.
+	if err := reprokit.ListenAndServe(":"+port, r.Handler()); err != nil {
 		log.Fatal("Server Shutdown:", err)
 	}
-	// catching ctx.Done(). timeout of 5 seconds.
-	select {
-	case <-ctx.Done():
-		log.Println("timeout of 5 seconds.")
-	}
 	log.Println("Server exiting")
 }